@@ -0,0 +1,275 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/savaki/properties"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// answers file entry holding the seed for random values, for when --seed isn't given
+const answerSeed = "_seed"
+
+// loadAnswers reads the answers to a template's fields from a .properties,
+// .json or .yaml file, or from the generation record of an earlier project.
+// List values, such as modules: [api, worker], are joined with commas so they
+// can be looped over.
+func loadAnswers(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var answers map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".properties":
+		answers, err = parsePropertiesAnswers(data)
+	case ".json":
+		answers, err = parseJSONAnswers(data)
+	case ".yaml", ".yml":
+		answers, err = parseYAMLAnswers(data)
+	default:
+		return nil, fmt.Errorf("%s: unknown answers format; expected .properties, .json or .yaml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return answers, nil
+}
+
+func parsePropertiesAnswers(data []byte) (map[string]string, error) {
+	p, err := properties.Load(data, properties.UTF8)
+	if err != nil {
+		return nil, err
+	}
+
+	answers := map[string]string{}
+	for _, key := range p.Keys() {
+		answers[key] = p.GetString(key, "")
+	}
+	return answers, nil
+}
+
+func parseJSONAnswers(data []byte) (map[string]string, error) {
+	values := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return nil, err
+	}
+
+	if recorded, ok := values["answers"].(map[string]interface{}); ok {
+		// a generation record written by g8 new; see record
+		values = recorded
+	}
+
+	answers := map[string]string{}
+	for key, value := range values {
+		answer, err := jsonAnswer(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", key, err)
+		}
+		answers[key] = answer
+	}
+	return answers, nil
+}
+
+// jsonAnswer converts a JSON value to the value of a field
+func jsonAnswer(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []interface{}:
+		items := []string{}
+		for _, item := range v {
+			answer, err := jsonAnswer(item)
+			if err != nil {
+				return "", err
+			}
+			if _, isList := item.([]interface{}); isList {
+				return "", fmt.Errorf("lists may not be nested")
+			}
+			items = append(items, answer)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("expected a string, number, boolean or list")
+	}
+}
+
+// parseYAMLAnswers reads the subset of YAML needed for answers: a mapping of
+// fields to scalars, either plain or quoted, or to lists written as - items or
+// as [a, b].  Nested mappings and block scalars aren't supported.
+func parseYAMLAnswers(data []byte) (map[string]string, error) {
+	answers := map[string]string{}
+	list := "" // key of the list whose - items are being read
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimRight(stripYAMLComment(scanner.Text()), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || trimmed == "---":
+			continue
+		case strings.HasPrefix(trimmed, "- ") || trimmed == "-":
+			if list == "" {
+				return nil, fmt.Errorf("line %d: list item outside of a list", number)
+			}
+			item, err := yamlScalar(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", number, err)
+			}
+			if answers[list] != "" {
+				answers[list] += ","
+			}
+			answers[list] += item
+			continue
+		case line[0] == ' ' || line[0] == '\t':
+			return nil, fmt.Errorf("line %d: nested mappings are not supported", number)
+		}
+
+		colon := strings.Index(line, ":")
+		if colon <= 0 || (colon+1 < len(line) && line[colon+1] != ' ') {
+			return nil, fmt.Errorf("line %d: expected key: value", number)
+		}
+		key, err := yamlScalar(strings.TrimSpace(line[:colon]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", number, err)
+		}
+		value := strings.TrimSpace(line[colon+1:])
+
+		list = ""
+		switch {
+		case value == "":
+			// either an empty value or the start of a list of - items
+			list = key
+			answers[key] = ""
+		case value == "|" || value == ">" || strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">"):
+			return nil, fmt.Errorf("line %d: block scalars are not supported", number)
+		case strings.HasPrefix(value, "["):
+			if !strings.HasSuffix(value, "]") {
+				return nil, fmt.Errorf("line %d: unterminated list", number)
+			}
+			items := []string{}
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				scalar, err := yamlScalar(item)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %s", number, err)
+				}
+				items = append(items, scalar)
+			}
+			answers[key] = strings.Join(items, ",")
+		case strings.HasPrefix(value, "{"):
+			return nil, fmt.Errorf("line %d: nested mappings are not supported", number)
+		default:
+			if answers[key], err = yamlScalar(value); err != nil {
+				return nil, fmt.Errorf("line %d: %s", number, err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+// stripYAMLComment removes a # comment from the end of line, ignoring #
+// within quotes or words
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// yamlScalar returns the value of a plain, single quoted or double quoted
+// YAML scalar
+func yamlScalar(text string) (string, error) {
+	switch {
+	case text == "~" || text == "null":
+		return "", nil
+	case strings.HasPrefix(text, `"`):
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted string %s", text)
+		}
+		return value, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return "", fmt.Errorf("invalid quoted string %s", text)
+		}
+		return strings.Replace(text[1:len(text)-1], "''", "'", -1), nil
+	default:
+		return text, nil
+	}
+}
+
+// unknownAnswers returns the sorted keys of the answers that are neither
+// properties of the template nor built in fields
+func unknownAnswers(answers map[string]string, p *properties.Properties, builtins map[string]string) []string {
+	known := map[string]bool{}
+	for _, key := range p.Keys() {
+		known[key] = true
+	}
+	for key := range builtins {
+		known[key] = true
+	}
+
+	unknown := []string{}
+	for key := range answers {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
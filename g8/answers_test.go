@@ -0,0 +1,98 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"github.com/savaki/properties"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAnswers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "answers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	expected := map[string]string{
+		"name":         "My Service",
+		"organization": "com.acme",
+		"docker":       "yes",
+		"modules":      "api,worker",
+		"port":         "8080",
+	}
+
+	for name, text := range map[string]string{
+		"answers.properties": "name=My Service\norganization=com.acme\ndocker=yes\nmodules=api,worker\nport=8080\n",
+		"answers.json":       `{"name": "My Service", "organization": "com.acme", "docker": "yes", "modules": ["api", "worker"], "port": 8080}`,
+		"answers.yaml":       "# answers\nname: My Service\norganization: \"com.acme\"\ndocker: yes # truthy\nmodules:\n  - api\n  - 'worker'\nport: 8080\n",
+		"answers.yml":        "---\nname: 'My Service'\norganization: com.acme\ndocker: \"yes\"\nmodules: [api, worker]\nport: 8080\n",
+	} {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte(text), 0644)
+
+		Convey("Answers can be read from "+name, t, func() {
+			answers, err := loadAnswers(path)
+			So(err, ShouldBeNil)
+			So(answers, ShouldResemble, expected)
+		})
+	}
+
+	Convey("Unsupported answers are errors", t, func() {
+		for name, text := range map[string]string{
+			"answers.txt":       "name=x",
+			"nested.yaml":       "name: x\nparent:\n  child: y\n",
+			"block.yaml":        "description: |\n  text\n",
+			"item.yaml":         "- api\n",
+			"nested.json":       `{"parent": {"child": "y"}}`,
+			"invalid.json":      `{"name": `,
+			"unterminated.yaml": "modules: [api, worker\n",
+		} {
+			path := filepath.Join(dir, name)
+			ioutil.WriteFile(path, []byte(text), 0644)
+
+			_, err := loadAnswers(path)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, path+": ")
+		}
+	})
+
+	Convey("YAML comments are only recognized outside of quotes and words", t, func() {
+		answers, err := parseYAMLAnswers([]byte("a: \"x # y\"\nb: c#d # comment\nc: ~\n"))
+		So(err, ShouldBeNil)
+		So(answers, ShouldResemble, map[string]string{"a": "x # y", "b": "c#d", "c": ""})
+	})
+}
+
+func TestUnknownAnswers(t *testing.T) {
+	Convey("Answers that aren't fields are reported", t, func() {
+		p, _ := properties.Load([]byte("name=x\nverbatim=*.sh\n"), properties.UTF8)
+		answers := map[string]string{"name": "a", "nmae": "b", "year": "2015", "verbatim": "*.js", "extra": "c"}
+
+		So(unknownAnswers(answers, p, map[string]string{"year": "2015"}), ShouldResemble, []string{"extra", "nmae"})
+	})
+}
@@ -0,0 +1,75 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"github.com/savaki/go-giter8/git"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+)
+
+// clock returns the current time; tests replace it to freeze time
+var clock = time.Now
+
+// generationTime returns the time the project is generated.  Setting
+// SOURCE_DATE_EPOCH, in seconds since the epoch, freezes it so output is
+// reproducible.
+func generationTime() time.Time {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		if seconds, err := strconv.ParseInt(epoch, 10, 64); err == nil {
+			return time.Unix(seconds, 0).UTC()
+		}
+	}
+	return clock()
+}
+
+// builtinFields returns the fields available to every template without being
+// declared in default.properties.  Properties of the same name take precedence.
+func builtinFields(client *git.Git) map[string]string {
+	now := generationTime()
+	fields := map[string]string{
+		"year": strconv.Itoa(now.Year()),
+		"now":  now.Format(time.RFC3339),
+		"date": now.Format("2006-01-02"),
+		"user": currentUser(),
+	}
+
+	if name, err := client.Config("user.name"); err == nil {
+		fields["gitUserName"] = name
+	}
+	if email, err := client.Config("user.email"); err == nil {
+		fields["gitUserEmail"] = email
+	}
+
+	return fields
+}
+
+// currentUser returns the login name of the user running g8
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
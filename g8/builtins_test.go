@@ -0,0 +1,64 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"github.com/savaki/go-giter8/git"
+	. "github.com/smartystreets/goconvey/convey"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBuiltinFields(t *testing.T) {
+	defer func(c func() time.Time) { clock = c }(clock)
+	clock = func() time.Time {
+		return time.Date(2015, time.March, 14, 9, 26, 53, 0, time.UTC)
+	}
+
+	// a git that cannot be run leaves the git fields undefined
+	client := git.New("/nonexistent/git", "")
+
+	Convey("Built in fields use the frozen clock", t, func() {
+		fields := builtinFields(client)
+		So(fields["year"], ShouldEqual, "2015")
+		So(fields["now"], ShouldEqual, "2015-03-14T09:26:53Z")
+		So(fields["date"], ShouldEqual, "2015-03-14")
+
+		_, ok := fields["gitUserName"]
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("SOURCE_DATE_EPOCH takes precedence over the clock", t, func() {
+		os.Setenv("SOURCE_DATE_EPOCH", "0")
+		defer os.Unsetenv("SOURCE_DATE_EPOCH")
+
+		So(builtinFields(client)["year"], ShouldEqual, "1970")
+	})
+
+	Convey("Built in fields can be formatted", t, func() {
+		value, err := resolveDefault("copyright", `$now;format="date:Jan 2006"$`, builtinFields(client), "missingkey=error", nil)
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "Mar 2015")
+	})
+}
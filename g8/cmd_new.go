@@ -23,13 +23,18 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/codegangsta/cli"
+	"github.com/savaki/go-giter8/git"
 	"github.com/savaki/go-giter8/template"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"unicode/utf8"
 )
 
 var commandNew = cli.Command{
@@ -38,6 +43,10 @@ var commandNew = cli.Command{
 	Flags: []cli.Flag{
 		flagGit,
 		flagVerbose,
+		flagLenient,
+		flagSeed,
+		flagYes,
+		flagAnswers,
 	},
 	Action: newAction,
 }
@@ -53,58 +62,392 @@ func newAction(c *cli.Context) {
 	err := exportRepo(opts.Git, opts.Repo)
 	check(err)
 
+	if opts.AnswersFile != "" {
+		answers, err := loadAnswers(opts.AnswersFile)
+		check(err)
+		opts.setAnswers(answers)
+	}
+
+	// shared by defaults and files so a seeded run is reproducible from start to finish
+	funcs := opts.Funcs()
+
 	// prompt the user to override the default properties
-	fields, err := readFields(opts.Repo)
+	client := git.New(opts.Git, "")
+	builtins := builtinFields(client)
+	p := loadProperties(opts.Repo)
+	fields, err := readFields(p, opts, builtins, funcs)
 	check(err)
 
 	// render the contents
-	err = newProject(opts.Repo, fields)
+	err = newProject(opts, fields, funcs, newRecord(client, Path(opts.Repo), p, opts, fields, builtins))
 	check(err)
 }
 
-func newProject(repo string, fields map[string]string) error {
+func newProject(opts Options, fields map[string]string, funcs template.FuncMap, r record) error {
 	target := template.Normalize(fields["name"])
 	if target == "" {
 		check(errors.New("no name parameter defined"))
 	}
 
-	codebase := Path(repo, "src/main/g8")
-	prefix := len(codebase)
-	return filepath.Walk(codebase, func(path string, f os.FileInfo, err error) error {
-		if f.IsDir() {
-			return nil
-		}
+	if err := generate(Path(opts.Repo, "src/main/g8"), target, opts, fields, funcs); err != nil {
+		return err
+	}
+
+	// record how the project was generated so it can be regenerated later
+	return writeRecord(target, r)
+}
+
+// generate renders the template in codebase into the directory target
+func generate(codebase, target string, opts Options, fields map[string]string, funcs template.FuncMap) error {
+	verbatim := strings.Fields(fields[fieldVerbatim])
+	binary := strings.Fields(fields[fieldBinary])
+	partials := []string{defaultPartials}
+	if value, ok := fields[fieldPartials]; ok {
+		partials = strings.Fields(value)
+	}
+	delims := strings.Fields(fields[fieldDelims])
+	if len(delims) != 0 && len(delims) != 2 {
+		return fmt.Errorf("%s must be a left and a right delimiter separated by a space e.g. [[ ]], got %q", fieldDelims, fields[fieldDelims])
+	}
+
+	// references to undefined fields are collected across all files so they
+	// can be reported together
+	undefined := []string{}
 
-		_, err = ioutil.ReadFile(path)
+	r := &renderer{
+		fields:     fields,
+		missingKey: opts.MissingKey(),
+		funcs:      funcs,
+		partials:   template.NewPartials(template.DirLoader(codebase)),
+		native:     strings.Fields(fields[fieldNative]),
+		delims:     delims,
+	}
+
+	paths := newPathCache(r)
+
+	prefix := len(codebase)
+	err := filepath.Walk(codebase, func(path string, f os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
 		relative := path[prefix:] // path is absolute; let's strip off the prefix
-		destBytes, err := template.Render([]byte(target+relative), fields)
+		if relative == "/"+metadataFile {
+			// describes the fields rather than being part of the project
+			return nil
+		}
+		if relative != "" && matches(partials, relative) {
+			// partials are only used through $include$
+			if f.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		outputs, err := paths.renderPath(relative)
+		if e, isUndefined := err.(*template.UndefinedError); isUndefined {
+			undefined = append(undefined, e.Error())
+			if f.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		if err != nil {
 			return err
 		}
-		dest := string(destBytes)
+		if len(outputs) == 0 {
+			// a segment of the path rendered to nothing e.g. $if(docker.truthy)$Dockerfile$endif$
+			if f.IsDir() {
+				if Verbose {
+					fmt.Printf("skipping directory, %s\n", relative)
+				}
+				return filepath.SkipDir
+			}
+			if Verbose {
+				fmt.Printf("skipping %s\n", relative)
+			}
+			return nil
+		}
 
-		// ensure the directory exists
-		dirname := filepath.Dir(dest)
-		if !exists(dirname) {
-			fmt.Printf("creating directory, %s\n", dirname)
-			os.MkdirAll(dirname, 0755)
+		if f.IsDir() {
+			return nil
 		}
 
-		data, err := ioutil.ReadFile(path)
-		if err != nil {
-			return err
+		// ensure the directories exist
+		for _, o := range outputs {
+			dirname := filepath.Dir(target + o.path)
+			if !exists(dirname) {
+				fmt.Printf("creating directory, %s\n", dirname)
+				os.MkdirAll(dirname, 0755)
+			}
 		}
 
-		output, err := template.Render(data, fields)
+		// sniff the start of the file to see whether it's safe to render
+		head, err := sniff(path)
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("writing %s\n", dest)
-		return ioutil.WriteFile(dest, output, f.Mode().Perm())
+		// files are streamed rather than read into memory, so templates may
+		// hold large files such as database dumps
+		for _, o := range outputs {
+			dest := target + o.path
+			switch {
+			case matches(binary, relative) || isBinary(head):
+				fmt.Printf("copying %s\n", dest)
+				err = copyPath(dest, path, f.Mode().Perm())
+			case matches(verbatim, relative):
+				fmt.Printf("writing %s\n", dest)
+				err = copyPath(dest, path, f.Mode().Perm())
+			default:
+				err = o.write(relative, path, dest, f.Mode().Perm())
+			}
+			if e, isUndefined := err.(*template.UndefinedError); isUndefined {
+				undefined = append(undefined, e.Error())
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	if len(undefined) > 0 {
+		return errors.New(strings.Join(undefined, "\n"))
+	}
+	return nil
+}
+
+// templateName names a template file after its path within the template repo
+func templateName(relative string) string {
+	return "src/main/g8" + relative
+}
+
+// renderer renders template files and paths against the answers given
+type renderer struct {
+	fields     map[string]string
+	missingKey string             // option for undefined fields e.g. missingkey=error; empty for the default
+	funcs      template.FuncMap   // formatters taking precedence over the registered ones
+	partials   *template.Partials // partials for $include$, shared by every file; nil if includes aren't supported
+	native     []string           // glob patterns of files written in native Go template syntax
+	delims     []string           // left and right delimiters of native templates; empty for {{ }}
+}
+
+// parse parses the giter8 text with the renderer's formatters and options
+func (r *renderer) parse(name string, text []byte) (*template.Template, error) {
+	t := template.New(name).Funcs(r.funcs).IncludesFrom(r.partials)
+	if r.missingKey != "" {
+		t.Option(r.missingKey)
+	}
+	return t.Parse(text)
+}
+
+// render renders the giter8 text against the fields
+func (r *renderer) render(name string, text []byte) ([]byte, error) {
+	t, err := r.parse(name, text)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+	if err := t.Execute(buffer, r.fields); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// stream renders the giter8 template read from in to w against the fields
+func (r *renderer) stream(name string, w io.Writer, in io.Reader) error {
+	t := template.New(name).Funcs(r.funcs).IncludesFrom(r.partials)
+	if r.missingKey != "" {
+		t.Option(r.missingKey)
+	}
+	return t.Stream(w, in, r.fields)
+}
+
+// renderNative renders the template written in native Go template syntax read
+// from in to w against the fields.  Unlike giter8 templates, the whole
+// template is read into memory.
+func (r *renderer) renderNative(name string, w io.Writer, in io.Reader) error {
+	text, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	t := template.Native(name, r.funcs)
+	if len(r.delims) == 2 {
+		t.Delims(r.delims[0], r.delims[1])
+	}
+	if r.missingKey == "missingkey=error" {
+		t.Option(r.missingKey)
+	} else {
+		// text/template has no equivalent of missingkey=keep, so undefined
+		// fields render empty as they do by default in giter8 files, rather
+		// than as <no value>
+		t.Option("missingkey=zero")
+	}
+	if _, err := t.Parse(string(text)); err != nil {
+		return err
+	}
+	return t.Execute(w, r.fields)
+}
+
+// with returns a renderer whose fields include the loop variables in bindings
+func (r *renderer) with(bindings map[string]string) *renderer {
+	if len(bindings) == 0 {
+		return r
+	}
+
+	fields := map[string]string{}
+	for key, value := range r.fields {
+		fields[key] = value
+	}
+	for key, value := range bindings {
+		fields[key] = value
+	}
+	clone := *r
+	clone.fields = fields
+	return &clone
+}
+
+// output is a path to generate along with the renderer for its contents
+type output struct {
+	path     string
+	renderer *renderer
+}
+
+// write renders the template file src to dest
+func (o output) write(relative, src, dest string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	render := o.renderer.stream
+	if matches(o.renderer.native, relative) {
+		render = o.renderer.renderNative
+	}
+
+	fmt.Printf("writing %s\n", dest)
+	return createFile(dest, mode, func(w io.Writer) error {
+		return render(templateName(relative), w, in)
+	})
+}
+
+// renderPath renders each segment of a template relative path.  A segment
+// holding a $for$ loop fans out into one path per item, with the loop variable
+// available to the rest of the path and to the file's contents.  Paths where
+// any segment renders to an empty name are dropped, so an empty result means
+// the file or directory should not be generated.
+func (r *renderer) renderPath(relative string) ([]output, error) {
+	segments := strings.Split(relative, "/")
+	outputs := []output{{renderer: r}}
+	for index := range segments {
+		var err error
+		if outputs, err = r.renderSegment(outputs, relative, segments, index); err != nil {
+			return nil, err
+		}
+	}
+
+	return outputs, nil
+}
+
+// renderSegment renders segments[index] of the path, relative, for each of the
+// outputs rendered from the segments before it
+func (r *renderer) renderSegment(outputs []output, relative string, segments []string, index int) ([]output, error) {
+	separator := "/"
+	if index == 0 {
+		separator = ""
+	}
+	if segments[index] == "" {
+		expanded := make([]output, len(outputs))
+		for i, o := range outputs {
+			expanded[i] = output{path: o.path + separator, renderer: o.renderer}
+		}
+		return expanded, nil
+	}
+
+	// report positions relative to the whole path rather than the segment
+	offset := utf8.RuneCountInString(templateName(strings.Join(segments[:index], "/") + "/"))
+
+	// parsed once, but executed for each output since earlier segments may
+	// have bound loop variables
+	t, err := r.parse(templateName(relative), []byte(segments[index]))
+	if err != nil {
+		return nil, offsetError(err, offset)
+	}
+
+	expanded := []output{}
+	for _, o := range outputs {
+		expansions, err := t.Expand(o.renderer.fields)
+		if err != nil {
+			return nil, offsetError(err, offset)
+		}
+		for _, e := range expansions {
+			if strings.TrimSpace(string(e.Text)) == "" {
+				continue
+			}
+			expanded = append(expanded, output{
+				path:     o.path + separator + string(e.Text),
+				renderer: o.renderer.with(e.Bindings),
+			})
+		}
+	}
+	return expanded, nil
+}
+
+// pathCache renders template relative paths, remembering the results so the
+// segments a directory shares with everything beneath it are rendered once
+// rather than once per file
+type pathCache struct {
+	renderer *renderer
+	paths    map[string][]output
+}
+
+func newPathCache(r *renderer) *pathCache {
+	return &pathCache{renderer: r, paths: map[string][]output{}}
+}
+
+// renderPath renders the path like renderer.renderPath, reusing the rendered
+// path of its directory
+func (c *pathCache) renderPath(relative string) ([]output, error) {
+	if outputs, ok := c.paths[relative]; ok {
+		return outputs, nil
+	}
+
+	index := strings.LastIndex(relative, "/")
+	if index < 0 {
+		return c.renderer.renderPath(relative)
+	}
+	parent, err := c.renderPath(relative[:index])
+	if err != nil {
+		return nil, err
+	}
+
+	segments := strings.Split(relative, "/")
+	outputs, err := c.renderer.renderSegment(parent, relative, segments, len(segments)-1)
+	if err != nil {
+		return nil, err
+	}
+	c.paths[relative] = outputs
+	return outputs, nil
+}
+
+// offsetError shifts the column of a template error by offset
+func offsetError(err error, offset int) error {
+	switch e := err.(type) {
+	case *template.Error:
+		e.Col += offset
+	case *template.UndefinedError:
+		for index := range e.Fields {
+			e.Fields[index].Col += offset
+		}
+	}
+	return err
 }
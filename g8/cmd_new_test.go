@@ -0,0 +1,410 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/savaki/go-giter8/template"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderPath(t *testing.T) {
+	fields := map[string]string{
+		"name":    "hello",
+		"package": "com.acme",
+		"docker":  "yes",
+		"helm":    "no",
+		"modules": "api,worker",
+	}
+
+	r := &renderer{fields: fields, missingKey: "missingkey=error"}
+
+	// paths returns the rendered paths of a template relative path
+	paths := func(relative string) []string {
+		outputs, err := r.renderPath(relative)
+		So(err, ShouldBeNil)
+
+		results := []string{}
+		for _, o := range outputs {
+			results = append(results, o.path)
+		}
+		return results
+	}
+
+	Convey("Each segment of the path is rendered", t, func() {
+		So(paths(`/src/$package;format="packaged"$/$name__Camel$.scala`), ShouldResemble, []string{"/src/com/acme/Hello.scala"})
+	})
+
+	Convey("A conditional segment that renders a name is kept", t, func() {
+		So(paths(`/$if(docker.truthy)$Dockerfile$endif$`), ShouldResemble, []string{"/Dockerfile"})
+	})
+
+	Convey("A segment that renders to an empty name is skipped", t, func() {
+		So(paths(`/$if(helm.truthy)$chart$endif$/values.yaml`), ShouldResemble, []string{})
+	})
+
+	Convey("A segment holding a loop fans out into one path per item", t, func() {
+		So(paths(`/cmd/$for(m in modules)$$m$$endfor$/$m__Camel$.go`), ShouldResemble, []string{
+			"/cmd/api/Api.go",
+			"/cmd/worker/Worker.go",
+		})
+	})
+
+	Convey("Loop variables are available to the contents of each path", t, func() {
+		outputs, err := r.renderPath(`/$for(m in modules)$$m$.txt$endfor$`)
+		So(err, ShouldBeNil)
+		So(len(outputs), ShouldEqual, 2)
+
+		content, err := outputs[1].renderer.render("test", []byte(`$name$ $m$`))
+		So(err, ShouldBeNil)
+		So(string(content), ShouldEqual, "hello worker")
+	})
+
+	Convey("An invalid segment is an error", t, func() {
+		_, err := r.renderPath(`/src/$name__uper$.scala`)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `template: src/main/g8/src/$name__uper$.scala:1:24: unknown formatter "uper" in $name__uper$; did you mean "upper"?`)
+	})
+
+	Convey("Undefined fields in a path are reported", t, func() {
+		_, err := r.renderPath(`/src/$nmae$.scala`)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `template: src/main/g8/src/$nmae$.scala:1:17: undefined field "nmae" in $nmae$; did you mean "name"?`)
+	})
+}
+
+func TestRenderInclude(t *testing.T) {
+	codebase, err := ioutil.TempDir("", "g8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(codebase)
+
+	os.MkdirAll(filepath.Join(codebase, "partials"), 0755)
+	ioutil.WriteFile(filepath.Join(codebase, "partials", "license.txt"), []byte("// Copyright $year$ $name$\n"), 0644)
+
+	r := &renderer{
+		fields:   map[string]string{"name": "acme", "year": "2015"},
+		partials: template.NewPartials(template.DirLoader(codebase)),
+	}
+
+	Convey("Partials are read from the template root and rendered with the answers", t, func() {
+		output, err := r.render(templateName("/main.go"), []byte("$include(\"partials/license.txt\")$\npackage main\n"))
+		So(err, ShouldBeNil)
+		So(string(output), ShouldEqual, "// Copyright 2015 acme\npackage main\n")
+	})
+
+	Convey("Only the partials directory at the template root is excluded from the output", t, func() {
+		So(matches([]string{defaultPartials}, "/partials"), ShouldBeTrue)
+		So(matches([]string{defaultPartials}, "/src/partials"), ShouldBeFalse)
+		So(matches([]string{defaultPartials}, "/src/partials.go"), ShouldBeFalse)
+
+		os.MkdirAll(filepath.Join(codebase, "layouts", "partials"), 0755)
+		ioutil.WriteFile(filepath.Join(codebase, "layouts", "partials", "header.html"), []byte("<h1>$name$</h1>\n"), 0644)
+		target, err := ioutil.TempDir("", "g8-project")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(target)
+
+		err = generate(codebase, target, Options{}, r.fields, nil)
+		So(err, ShouldBeNil)
+		So(exists(filepath.Join(target, "partials")), ShouldBeFalse)
+
+		data, err := ioutil.ReadFile(filepath.Join(target, "layouts", "partials", "header.html"))
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "<h1>acme</h1>\n")
+	})
+}
+
+func TestOutputWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "g8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := &renderer{
+		fields:     map[string]string{"name": "Order Service", "modules": "api,worker"},
+		missingKey: "missingkey=error",
+		native:     []string{"*.go"},
+		delims:     []string{"[[", "]]"},
+	}
+	o := output{renderer: r}
+
+	// write renders text as the template relative path to a file in dir
+	write := func(relative, text string) (string, error) {
+		src := filepath.Join(dir, "src")
+		ioutil.WriteFile(src, []byte(text), 0644)
+
+		dest := filepath.Join(dir, filepath.Base(relative))
+		if err := o.write(relative, src, dest, 0644); err != nil {
+			return "", err
+		}
+		data, err := ioutil.ReadFile(dest)
+		return string(data), err
+	}
+
+	Convey("Template files are rendered to the destination", t, func() {
+		value, err := write("/README.md", "# $name$\n[[ .name ]]\n")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "# Order Service\n[[ .name ]]\n")
+	})
+
+	Convey("Files matching the native patterns are rendered as Go templates", t, func() {
+		value, err := write("/main.go", "// [[ .name | norm ]][[ range items .modules ]] [[ . ]][[ end ]] $name$\n")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "// order-service api worker $name$\n")
+	})
+
+	Convey("Undefined fields are errors in strict mode and nothing is written", t, func() {
+		_, err := write("/app.go", "[[ .nmae ]]")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, `map has no entry for key "nmae"`)
+
+		_, err = write("/app.txt", "$nmae$")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, `undefined field "nmae"`)
+
+		So(exists(filepath.Join(dir, "app.go")), ShouldBeFalse)
+		So(exists(filepath.Join(dir, "app.txt")), ShouldBeFalse)
+
+		files, _ := ioutil.ReadDir(dir)
+		So(len(files), ShouldEqual, 3) // src, README.md and main.go but no temporary files
+	})
+
+	Convey("Undefined fields in native files render empty when not strict", t, func() {
+		for _, missingKey := range []string{"", "missingkey=keep"} {
+			lenient := output{renderer: &renderer{fields: r.fields, missingKey: missingKey, native: r.native}}
+			src := filepath.Join(dir, "src")
+			ioutil.WriteFile(src, []byte("name: {{ .name }}, owner: {{ .owner }}\n"), 0644)
+
+			dest := filepath.Join(dir, "lenient.go")
+			So(lenient.write("/lenient.go", src, dest, 0644), ShouldBeNil)
+			data, err := ioutil.ReadFile(dest)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "name: Order Service, owner: \n")
+		}
+	})
+}
+
+func TestPathCache(t *testing.T) {
+	r := &renderer{
+		fields:     map[string]string{"name": "hello", "package": "com.acme", "modules": "api,worker"},
+		missingKey: "missingkey=error",
+	}
+
+	Convey("Cached paths match the rendered paths", t, func() {
+		paths := newPathCache(r)
+		for _, relative := range []string{
+			"",
+			"/src",
+			`/src/$package;format="packaged"$`,
+			`/src/$package;format="packaged"$/$name__Camel$.scala`,
+			`/cmd/$for(m in modules)$$m$$endfor$/$m$.go`,
+			`/cmd/$for(m in modules)$$m$$endfor$/README.md`,
+		} {
+			expected, err := r.renderPath(relative)
+			So(err, ShouldBeNil)
+
+			outputs, err := paths.renderPath(relative)
+			So(err, ShouldBeNil)
+			So(len(outputs), ShouldEqual, len(expected))
+			for index := range outputs {
+				So(outputs[index].path, ShouldEqual, expected[index].path)
+				So(outputs[index].renderer.fields, ShouldResemble, expected[index].renderer.fields)
+			}
+		}
+	})
+
+	Convey("Errors are reported against the path that holds them", t, func() {
+		_, err := newPathCache(r).renderPath(`/src/$nmae$/Main.scala`)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `template: src/main/g8/src/$nmae$:1:17: undefined field "nmae" in $nmae$; did you mean "name"?`)
+	})
+}
+
+func TestGenerate(t *testing.T) {
+	codebase, target := syntheticTemplate(t, 20)
+	defer os.RemoveAll(codebase)
+	defer os.RemoveAll(target)
+
+	Convey("Every file of the template is generated", t, func() {
+		err := generate(codebase, target, Options{}, syntheticFields, nil)
+		So(err, ShouldBeNil)
+
+		data, err := ioutil.ReadFile(filepath.Join(target, "src/com/acme/module00/OrderServiceFile0003.scala"))
+		So(err, ShouldBeNil)
+		So(string(data), ShouldContainSubstring, "package com.acme.module00")
+		So(string(data), ShouldContainSubstring, "class OrderServiceFile0003")
+		So(exists(filepath.Join(target, "Dockerfile")), ShouldBeTrue)
+	})
+
+	Convey("The field metadata is not generated", t, func() {
+		ioutil.WriteFile(filepath.Join(codebase, metadataFile), []byte("port.pattern=[0-9]+$\n"), 0644)
+		defer os.Remove(filepath.Join(codebase, metadataFile))
+
+		err := generate(codebase, target, Options{}, syntheticFields, nil)
+		So(err, ShouldBeNil)
+		So(exists(filepath.Join(target, metadataFile)), ShouldBeFalse)
+	})
+}
+
+// fields for the template created by syntheticTemplate
+var syntheticFields = map[string]string{
+	"name":    "Order Service",
+	"package": "com.acme",
+	"docker":  "yes",
+}
+
+// syntheticTemplate creates a template with the given number of source files,
+// spread across directories of 100 files, returning the template's src/main/g8
+// directory and a directory to generate it into
+func syntheticTemplate(tb testing.TB, files int) (codebase, target string) {
+	codebase, err := ioutil.TempDir("", "g8-template")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	target, err = ioutil.TempDir("", "g8-project")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	content := []byte(`// $name$ (c) $year;format="default("2015")"$
+package $package$.module$module$
+
+$if(docker.truthy)$
+// packaged with docker
+$endif$
+class $name__Camel$File$file$ {
+  val name = "$name;format="norm"$"
+  val price = \$100
+}
+`)
+	ioutil.WriteFile(filepath.Join(codebase, "$if(docker.truthy)$Dockerfile$endif$"), []byte("FROM scratch\n"), 0644)
+	for i := 0; i < files; i++ {
+		dir := filepath.Join(codebase, "src", `$package;format="packaged"$`, fmt.Sprintf("module%02d", i/100))
+		os.MkdirAll(dir, 0755)
+
+		text := bytes.Replace(content, []byte("$module$"), []byte(fmt.Sprintf("%02d", i/100)), -1)
+		text = bytes.Replace(text, []byte("$file$"), []byte(fmt.Sprintf("%04d", i)), -1)
+		name := fmt.Sprintf("$name__Camel$File%04d.scala", i)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), text, 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return codebase, target
+}
+
+// relativePaths returns the template relative paths of the files and
+// directories in codebase
+func relativePaths(tb testing.TB, codebase string) []string {
+	paths := []string{}
+	err := filepath.Walk(codebase, func(path string, f os.FileInfo, err error) error {
+		paths = append(paths, path[len(codebase):])
+		return err
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return paths
+}
+
+func BenchmarkRenderPaths(b *testing.B) {
+	codebase, target := syntheticTemplate(b, 5000)
+	defer os.RemoveAll(codebase)
+	defer os.RemoveAll(target)
+
+	relatives := relativePaths(b, codebase)
+	r := &renderer{fields: syntheticFields, missingKey: "missingkey=error"}
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, relative := range relatives {
+				if _, err := r.renderPath(relative); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			paths := newPathCache(r)
+			for _, relative := range relatives {
+				if _, err := paths.renderPath(relative); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	codebase, target := syntheticTemplate(b, 5000)
+	defer os.RemoveAll(codebase)
+	defer os.RemoveAll(target)
+
+	stdout := os.Stdout
+	os.Stdout, _ = os.Open(os.DevNull)
+	defer func() { os.Stdout = stdout }()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dest := filepath.Join(target, fmt.Sprint(i))
+		if err := generate(codebase, dest, Options{}, syntheticFields, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderInclude(b *testing.B) {
+	codebase, err := ioutil.TempDir("", "g8-template")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(codebase)
+
+	os.MkdirAll(filepath.Join(codebase, "partials"), 0755)
+	license := bytes.Repeat([]byte("// $name$ (c) $year;format=\"default(\"2015\")\"$ $organization;format=\"upper\"$\n"), 20)
+	ioutil.WriteFile(filepath.Join(codebase, "partials", "license.txt"), license, 0644)
+
+	r := &renderer{
+		fields:   map[string]string{"name": "Order Service", "organization": "acme"},
+		partials: template.NewPartials(template.DirLoader(codebase)),
+	}
+	text := []byte("$include(\"partials/license.txt\")$\npackage $name;format=\"norm,word\"$\n")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// as for a template of 1,000 files, each including the license
+		for file := 0; file < 1000; file++ {
+			if _, err := r.render(templateName("/main.go"), text); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
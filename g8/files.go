@@ -0,0 +1,129 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// number of bytes examined when deciding whether a file is binary
+const sniffLen = 512
+
+// matches reports whether the template relative path matches any of the glob
+// patterns.  Patterns without a slash, e.g. *.sh, are matched against the file
+// name; patterns with one against the whole relative path.
+func matches(patterns []string, relative string) bool {
+	relative = strings.TrimPrefix(relative, "/")
+	for _, pattern := range patterns {
+		name := filepath.Base(relative)
+		if strings.Contains(pattern, "/") {
+			name = relative
+		}
+		if ok, _ := filepath.Match(strings.TrimPrefix(pattern, "/"), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isBinary guesses whether the file beginning with head is binary.  Files
+// containing NUL bytes are binary, as is anything content sniffing doesn't
+// recognize as text e.g. images, fonts and archives such as jars.
+func isBinary(head []byte) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	if len(head) == 0 {
+		return false
+	}
+
+	contentType := http.DetectContentType(head)
+	return !strings.HasPrefix(contentType, "text/")
+}
+
+// sniff returns the start of the file at path
+func sniff(path string) ([]byte, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(in, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// copyPath streams the file at src to dest
+func copyPath(dest, src string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return copyFile(dest, in, mode)
+}
+
+// copyFile streams the contents of r to dest and sets its permissions to
+// mode regardless of the umask
+func copyFile(dest string, r io.Reader, mode os.FileMode) error {
+	return createFile(dest, mode, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// createFile creates dest with the output of write and sets its permissions
+// to mode regardless of the umask.  The output is written to a temporary file
+// that replaces dest only once write succeeds, so a failed write never leaves
+// a partial file behind.
+func createFile(dest string, mode os.FileMode, write func(w io.Writer) error) error {
+	out, err := ioutil.TempFile(filepath.Dir(dest), "."+filepath.Base(dest)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name()) // no-op once renamed
+
+	if err := write(out); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(out.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(out.Name(), dest)
+}
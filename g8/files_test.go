@@ -0,0 +1,90 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	patterns := []string{"*.sh", "*.js", "scripts/*.php"}
+
+	Convey("Files whose name matches a pattern match", t, func() {
+		So(matches(patterns, "/build.sh"), ShouldBeTrue)
+		So(matches(patterns, "/src/main/web/app.js"), ShouldBeTrue)
+	})
+
+	Convey("Patterns containing a slash match the relative path", t, func() {
+		So(matches(patterns, "/scripts/index.php"), ShouldBeTrue)
+		So(matches(patterns, "/web/index.php"), ShouldBeFalse)
+	})
+
+	Convey("Other files don't match", t, func() {
+		So(matches(patterns, "/build.sbt"), ShouldBeFalse)
+		So(matches(nil, "/build.sh"), ShouldBeFalse)
+	})
+}
+
+func TestIsBinary(t *testing.T) {
+	Convey("Text files are not binary", t, func() {
+		So(isBinary([]byte("name=$name$\n")), ShouldBeFalse)
+		So(isBinary([]byte("<html><body>émile</body></html>")), ShouldBeFalse)
+		So(isBinary([]byte{}), ShouldBeFalse)
+	})
+
+	Convey("Files containing NUL bytes are binary", t, func() {
+		So(isBinary([]byte("abc\x00def")), ShouldBeTrue)
+	})
+
+	Convey("Images and archives are binary", t, func() {
+		So(isBinary([]byte("\x89PNG\x0D\x0A\x1A\x0A")), ShouldBeTrue)
+		So(isBinary([]byte("PK\x03\x04META-INF/MANIFEST.MF")), ShouldBeTrue)
+	})
+}
+
+func TestCopyFile(t *testing.T) {
+	Convey("#copyFile writes the content byte-for-byte with the given mode", t, func() {
+		dir, err := ioutil.TempDir("", "g8")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		dest := filepath.Join(dir, "gradlew")
+		content := []byte("\x89PNG\x00$name$")
+
+		err = copyFile(dest, bytes.NewReader(content), 0751)
+		So(err, ShouldBeNil)
+
+		data, err := ioutil.ReadFile(dest)
+		So(err, ShouldBeNil)
+		So(data, ShouldResemble, content)
+
+		info, err := os.Stat(dest)
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, os.FileMode(0751))
+	})
+}
@@ -24,16 +24,25 @@ package main
 
 import (
 	"github.com/codegangsta/cli"
+	"github.com/savaki/go-giter8/template"
 )
 
 const (
 	fieldGit     = "git"
 	fieldVerbose = "verbose"
+	fieldLenient = "lenient"
+	fieldSeed    = "seed"
+	fieldYes     = "yes"
+	fieldAnswers = "answers"
 )
 
 var (
-	flagGit     = cli.StringFlag{fieldGit, "/usr/bin/git", "path to the git binary", "GIT"}
-	flagVerbose = cli.BoolFlag{fieldVerbose, "additional debugging", "VERBOSE"}
+	flagGit     = cli.StringFlag{Name: fieldGit, Value: "/usr/bin/git", Usage: "path to the git binary", EnvVar: "GIT"}
+	flagVerbose = cli.BoolFlag{Name: fieldVerbose, Usage: "additional debugging", EnvVar: "VERBOSE"}
+	flagLenient = cli.BoolFlag{Name: fieldLenient, Usage: "leave references to undefined fields untouched rather than failing"}
+	flagSeed    = cli.StringFlag{Name: fieldSeed, Usage: "seed for random values so the same seed always generates the same output", EnvVar: "G8_SEED"}
+	flagYes     = cli.BoolFlag{Name: fieldYes + ", no-input", Usage: "accept the defaults rather than prompting; fields may be set with --name=value"}
+	flagAnswers = cli.StringFlag{Name: fieldAnswers, Usage: "file of answers to the template's fields in .properties, .json or .yaml format"}
 )
 
 var Verbose bool
@@ -42,6 +51,13 @@ type Options struct {
 	Verbose bool
 	Git     string
 	Repo    string
+	Lenient bool
+	Seed    string
+	NoInput bool              // accept defaults rather than prompting
+	Fields  map[string]string // fields given on the command line as --key=value
+
+	AnswersFile string            // path of the file of answers, if any
+	Answers     map[string]string // fields read from the answers file
 }
 
 func Opts(c *cli.Context) Options {
@@ -51,5 +67,53 @@ func Opts(c *cli.Context) Options {
 		Verbose: Verbose,
 		Git:     c.String(fieldGit),
 		Repo:    c.Args().First(),
+		Lenient: c.Bool(fieldLenient),
+		Seed:    c.String(fieldSeed),
+		NoInput: c.Bool(fieldYes),
+		Fields:  fieldArgs,
+
+		AnswersFile: c.String(fieldAnswers),
+	}
+}
+
+// setAnswers uses the answers read from the answers file for fields not given
+// on the command line.  The seed may also be set in the answers file.
+func (o *Options) setAnswers(answers map[string]string) {
+	if seed, ok := answers[answerSeed]; ok {
+		if o.Seed == "" {
+			o.Seed = seed
+		}
+		delete(answers, answerSeed)
+	}
+	o.Answers = answers
+}
+
+// given returns the fields given on the command line or in the answers file
+func (o Options) given() map[string]string {
+	fields := map[string]string{}
+	for key, value := range o.Answers {
+		fields[key] = value
+	}
+	for key, value := range o.Fields {
+		fields[key] = value
+	}
+	return fields
+}
+
+// MissingKey returns the template option controlling how references to
+// undefined fields are handled; strict unless --lenient was given
+func (o Options) MissingKey() string {
+	if o.Lenient {
+		return "missingkey=keep"
+	}
+	return "missingkey=error"
+}
+
+// Funcs returns the formatters that take precedence over the registered ones;
+// when a seed is given the random formatters draw from a reproducible source
+func (o Options) Funcs() template.FuncMap {
+	if o.Seed == "" {
+		return nil
 	}
+	return template.RandomFuncs(template.NewSeededReader(o.Seed))
 }
@@ -23,24 +23,36 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/codegangsta/cli"
 	"github.com/savaki/go-giter8/git"
+	"github.com/savaki/go-giter8/template"
 	"github.com/savaki/properties"
+	"io"
 	"log"
 	"os"
 	"strings"
 )
 
+// version of g8, recorded in the projects it generates
+const version = "0.1"
+
 func main() {
 	app := cli.NewApp()
 	app.Name = "giter8"
 	app.Usage = "generate templates using github"
-	app.Version = "0.1"
+	app.Version = version
 	app.Commands = []cli.Command{
 		commandNew,
 	}
-	app.Run(os.Args)
+
+	args := os.Args
+	if len(args) > 1 && commandNew.HasName(args[1]) {
+		// like giter8, fields may be given as --name=value along with the flags
+		args, fieldArgs = splitFieldArgs(args, commandNew.Flags)
+	}
+	app.Run(args)
 }
 
 func check(err error) {
@@ -74,29 +86,210 @@ func Path(dirs ...string) string {
 	return fmt.Sprintf("%s/.go-giter8/%s", os.Getenv("HOME"), subdir)
 }
 
-func readFields(repo string) (map[string]string, error) {
+// properties in default.properties that configure the template rather than
+// ask the user a question
+const (
+	// glob patterns of files copied without being rendered e.g. verbatim=*.sh *.js
+	fieldVerbatim = "verbatim"
+
+	// glob patterns of files always treated as binary e.g. binary=*.dat
+	fieldBinary = "binary"
+
+	// glob patterns of partials, available to $include$ but not generated
+	// themselves; defaults to defaultPartials
+	fieldPartials = "partials"
+
+	// glob patterns of files written in native Go template syntax e.g. native=*.go
+	fieldNative = "native"
+
+	// left and right delimiters of native templates e.g. delims=[[ ]]
+	fieldDelims = "delims"
+)
+
+// partials directory used when the template doesn't set the partials property;
+// only the directory at the root of the template, so directories of the same
+// name elsewhere, such as Hugo's layouts/partials, are generated as usual
+const defaultPartials = "/partials"
+
+// isConfig reports whether the property configures the template
+func isConfig(key string) bool {
+	switch key {
+	case fieldVerbatim, fieldBinary, fieldPartials, fieldNative, fieldDelims:
+		return true
+	default:
+		return false
+	}
+}
+
+// loadProperties reads the template's default.properties; a template without
+// one has no properties
+func loadProperties(repo string) *properties.Properties {
 	// assume giter8 format
 	path := Path(repo, "src/main/g8/default.properties")
 	p, err := properties.LoadFile(path, properties.UTF8)
 	if err != nil {
-		return map[string]string{}, nil
+		return properties.NewProperties()
+	}
+	return p
+}
+
+// readFields prompts the user for each property in p, starting from the built
+// in fields
+func readFields(p *properties.Properties, opts Options, builtins map[string]string, funcs template.FuncMap) (map[string]string, error) {
+	meta, err := loadMetadata(Path(opts.Repo, "src/main/g8", metadataFile))
+	if err != nil {
+		return nil, err
 	}
 
-	// ask the user for input on each of the fields
+	return collectFields(p, meta, opts, builtins, funcs, newPrompter(os.Stdin, os.Stdout).ask)
+}
+
+// collectFields determines the value of each property, in the order they are
+// declared.  Values given with --key=value or in the answers file are used as
+// is; otherwise ask is called with the default, unless --yes was given in
+// which case the default is accepted.  A blank answer also accepts the
+// default, as does every field once ask returns io.EOF.  Empty values are only
+// refused for required fields, or with --yes for fields with neither a default
+// nor required=false.  Answers that don't satisfy the field's metadata are
+// asked for again; values that weren't prompted for are checked together, and
+// every violation is reported.
+func collectFields(p *properties.Properties, meta map[string]*fieldMeta, opts Options, builtins map[string]string, funcs template.FuncMap, ask func(key, defaultValue string) (string, error)) (map[string]string, error) {
+	for _, key := range unknownAnswers(opts.Answers, p, builtins) {
+		fmt.Printf("warning: %s: %s is not a field of the template\n", opts.AnswersFile, key)
+	}
+	for _, key := range unknownMetadata(meta, p) {
+		fmt.Printf("warning: %s: %s is not a field of the template\n", metadataFile, key)
+	}
+
+	given := opts.given()
 	fields := map[string]string{}
+	for key, value := range builtins {
+		fields[key] = value
+	}
+	for key, value := range given {
+		fields[key] = value
+	}
+
+	interactive := !opts.NoInput
+	violations := []string{}
+	invalid := func(key string, err error) {
+		if err == errRequired {
+			violations = append(violations, fmt.Sprintf("%s: no value given; set it with --%s=value", key, key))
+		} else {
+			violations = append(violations, fmt.Sprintf("%s: %s", key, err))
+		}
+	}
+
 	for _, key := range p.Keys() {
 		defaultValue := p.GetString(key, "")
-		fmt.Printf("%s [%s]: ", key, defaultValue)
+		if isConfig(key) {
+			if _, ok := given[key]; !ok {
+				// template configuration rather than a question for the user
+				fields[key] = defaultValue
+			}
+			continue
+		}
+		m := meta[key]
+		if value, ok := given[key]; ok {
+			// an empty value given explicitly e.g. --name= is only refused for
+			// fields that must have a value
+			if err := m.check(value, true); err == errRequired {
+				violations = append(violations, fmt.Sprintf("%s: empty value not allowed", key))
+			} else if err != nil {
+				invalid(key, err)
+			}
+			continue
+		}
 
-		var value string
-		fmt.Scanln(&value)
+		// defaults may refer to earlier answers e.g. package=$organization$.$name;format="norm"$
+		defaultValue, err := resolveDefault(key, defaultValue, fields, opts.MissingKey(), funcs)
+		if err != nil {
+			return nil, err
+		}
 
-		if strings.TrimSpace(value) == "" {
-			fields[key] = defaultValue
-		} else {
+		if interactive && m != nil && m.description != "" {
+			fmt.Println(m.description)
+		}
+		for {
+			value := ""
+			if interactive {
+				if value, err = ask(m.label(key), defaultValue); err == io.EOF {
+					// input has run out, so accept the defaults from here on
+					interactive = false
+				} else if err != nil {
+					return nil, err
+				}
+			}
+			if strings.TrimSpace(value) == "" {
+				value = defaultValue
+			}
 			fields[key] = value
+
+			err := m.check(value, !opts.NoInput)
+			if err == nil {
+				break
+			}
+			if !interactive {
+				invalid(key, err)
+				break
+			}
+			fmt.Printf("invalid %s: %s\n", key, err)
 		}
 	}
 
+	if len(violations) > 0 {
+		return nil, fmt.Errorf("invalid answers:\n  %s", strings.Join(violations, "\n  "))
+	}
 	return fields, nil
 }
+
+// fieldArgs holds the fields given on the command line as --key=value
+var fieldArgs = map[string]string{}
+
+// splitFieldArgs separates the --key=value arguments that set fields from the
+// other arguments of the new command.  Arguments naming one of the command's
+// flags are left in place, as is everything following --.
+func splitFieldArgs(args []string, flags []cli.Flag) ([]string, map[string]string) {
+	set := flag.NewFlagSet("", flag.ContinueOnError)
+	for _, f := range flags {
+		f.Apply(set)
+	}
+	set.Bool("help", false, "")
+	set.Bool("h", false, "")
+
+	remaining := []string{}
+	fields := map[string]string{}
+	for index, arg := range args {
+		if arg == "--" {
+			remaining = append(remaining, args[index:]...)
+			break
+		}
+
+		equals := strings.Index(arg, "=")
+		if !strings.HasPrefix(arg, "--") || equals < 0 {
+			remaining = append(remaining, arg)
+			continue
+		}
+		key := arg[2:equals]
+		if key == "" || set.Lookup(key) != nil {
+			remaining = append(remaining, arg)
+			continue
+		}
+		fields[key] = arg[equals+1:]
+	}
+	return remaining, fields
+}
+
+// resolveDefault renders the default value of a property against the answers
+// given so far.  Like the template's files, references to undefined fields are
+// handled according to missingKey, so a typo is reported rather than leaving
+// the default empty.
+func resolveDefault(key, value string, fields map[string]string, missingKey string, funcs template.FuncMap) (string, error) {
+	r := &renderer{fields: fields, missingKey: missingKey, funcs: funcs}
+	rendered, err := r.render("default.properties:"+key, []byte(value))
+	if err != nil {
+		return "", err
+	}
+
+	return string(rendered), nil
+}
@@ -23,8 +23,170 @@
 package main
 
 import (
+	"github.com/savaki/properties"
+	. "github.com/smartystreets/goconvey/convey"
 	"testing"
 )
 
 func TestCompiles(t *testing.T) {
 }
+
+func TestResolveDefault(t *testing.T) {
+	fields := map[string]string{
+		"organization": "com.acme",
+		"name":         "Order Service",
+	}
+
+	Convey("Defaults can refer to earlier answers", t, func() {
+		value, err := resolveDefault("package", `$organization$.$name;format="norm,word"$`, fields, "missingkey=error", nil)
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "com.acme.orderservice")
+	})
+
+	Convey("Plain defaults are returned as is", t, func() {
+		value, err := resolveDefault("version", "0.1.0-SNAPSHOT", fields, "missingkey=error", nil)
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "0.1.0-SNAPSHOT")
+	})
+
+	Convey("Invalid defaults are reported with the property name", t, func() {
+		_, err := resolveDefault("package", `$organization;format="uper"$`, fields, "missingkey=error", nil)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "default.properties:package")
+	})
+
+	Convey("Typos in defaults are reported with a suggestion", t, func() {
+		_, err := resolveDefault("package", `$organization$.$nmae$`, fields, "missingkey=error", nil)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "default.properties:package")
+		So(err.Error(), ShouldContainSubstring, `undefined field "nmae"`)
+		So(err.Error(), ShouldContainSubstring, `did you mean "name"?`)
+
+		value, err := resolveDefault("package", `$organization$.$nmae$`, fields, "missingkey=keep", nil)
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "com.acme.$nmae$")
+	})
+}
+
+func TestResolveDefaultSeeded(t *testing.T) {
+	Convey("Seeded defaults are reproducible", t, func() {
+		resolve := func() string {
+			funcs := Options{Seed: "golden"}.Funcs()
+			value, err := resolveDefault("secret", `$name;format="hex(16)"$`, map[string]string{"name": "shop"}, "missingkey=error", funcs)
+			So(err, ShouldBeNil)
+			return value
+		}
+
+		first := resolve()
+		So(len(first), ShouldEqual, 16)
+		So(resolve(), ShouldEqual, first)
+	})
+}
+
+func TestCollectFields(t *testing.T) {
+	p, err := properties.Load([]byte(`name=My Service
+organization=
+package=$organization$.$name;format="norm,word"$
+verbatim=*.sh
+`), properties.UTF8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtins := map[string]string{"year": "2015"}
+
+	// answers returns an ask function that replies from the map, recording the
+	// fields asked about
+	answers := func(replies map[string]string, asked *[]string) func(string, string) (string, error) {
+		return func(key, defaultValue string) (string, error) {
+			*asked = append(*asked, key)
+			return replies[key], nil
+		}
+	}
+
+	Convey("Each property is asked about in order", t, func() {
+		asked := []string{}
+		fields, err := collectFields(p, nil, Options{}, builtins, nil, answers(map[string]string{"organization": "com.acme"}, &asked))
+		So(err, ShouldBeNil)
+		So(asked, ShouldResemble, []string{"name", "organization", "package"})
+		So(fields, ShouldResemble, map[string]string{
+			"year":         "2015",
+			"name":         "My Service",
+			"organization": "com.acme",
+			"package":      "com.acme.myservice",
+			"verbatim":     "*.sh",
+		})
+	})
+
+	Convey("Fields given on the command line are not asked about", t, func() {
+		asked := []string{}
+		opts := Options{Fields: map[string]string{"name": "Shop", "organization": "org.example", "year": "2020"}}
+		fields, err := collectFields(p, nil, opts, builtins, nil, answers(nil, &asked))
+		So(err, ShouldBeNil)
+		So(asked, ShouldResemble, []string{"package"})
+		So(fields["package"], ShouldEqual, "org.example.shop")
+		So(fields["year"], ShouldEqual, "2020")
+	})
+
+	Convey("Answers are used for fields not given on the command line", t, func() {
+		asked := []string{}
+		opts := Options{Fields: map[string]string{"name": "Shop"}}
+		opts.setAnswers(map[string]string{"name": "Ignored", "organization": "org.example", "_seed": "golden"})
+
+		fields, err := collectFields(p, nil, opts, builtins, nil, answers(nil, &asked))
+		So(err, ShouldBeNil)
+		So(asked, ShouldResemble, []string{"package"})
+		So(fields["name"], ShouldEqual, "Shop")
+		So(fields["organization"], ShouldEqual, "org.example")
+		So(opts.Seed, ShouldEqual, "golden")
+
+		_, ok := fields["_seed"]
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("A seed given with --seed takes precedence over the answers file", t, func() {
+		opts := Options{Seed: "flag"}
+		opts.setAnswers(map[string]string{"_seed": "golden"})
+		So(opts.Seed, ShouldEqual, "flag")
+	})
+
+	Convey("With --yes the defaults are accepted without asking", t, func() {
+		asked := []string{}
+		opts := Options{NoInput: true, Fields: map[string]string{"organization": "com.acme"}}
+		fields, err := collectFields(p, nil, opts, builtins, nil, answers(nil, &asked))
+		So(err, ShouldBeNil)
+		So(len(asked), ShouldEqual, 0)
+		So(fields["name"], ShouldEqual, "My Service")
+		So(fields["package"], ShouldEqual, "com.acme.myservice")
+	})
+
+	Convey("With --yes fields without a default must be given", t, func() {
+		asked := []string{}
+		_, err := collectFields(p, nil, Options{NoInput: true}, builtins, nil, answers(nil, &asked))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "invalid answers:\n  organization: no value given; set it with --organization=value")
+	})
+}
+
+func TestSplitFieldArgs(t *testing.T) {
+	Convey("Field arguments are separated from the flags", t, func() {
+		args, fields := splitFieldArgs([]string{
+			"g8", "new", "--git=/bin/git", "--name=my-service", "savaki/template.g8",
+			"--organization=com.acme", "--verbose", "--yes", "--seed=x", "--empty=", "--", "--after=1",
+		}, commandNew.Flags)
+
+		So(args, ShouldResemble, []string{
+			"g8", "new", "--git=/bin/git", "savaki/template.g8", "--verbose", "--yes", "--seed=x", "--", "--after=1",
+		})
+		So(fields, ShouldResemble, map[string]string{
+			"name":         "my-service",
+			"organization": "com.acme",
+			"empty":        "",
+		})
+	})
+
+	Convey("Aliases of flags are flags", t, func() {
+		args, fields := splitFieldArgs([]string{"g8", "new", "--no-input=true", "--help=1"}, commandNew.Flags)
+		So(args, ShouldResemble, []string{"g8", "new", "--no-input=true", "--help=1"})
+		So(len(fields), ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,218 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/savaki/properties"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// name of the optional file, alongside default.properties, describing the
+// template's fields e.g.
+//
+//	name.description=Name of the service, used for the artifact and DNS name
+//	name.validate=dns-label
+//	database.choices=postgres, mysql, none
+//	organization.required=true
+const metadataFile = "fields.properties"
+
+// fieldMeta describes a field of the template: how it's prompted for and which
+// values it accepts
+type fieldMeta struct {
+	description string
+	choices     []string
+	validators  []validator
+	required    bool // a value must be given, even when prompted for
+	optional    bool // may be left empty, even with --yes
+}
+
+// validator checks the value of a field
+type validator struct {
+	name  string // describes the values accepted e.g. a valid semantic version
+	valid func(value string) bool
+}
+
+// errRequired is returned by check for a required field left empty
+var errRequired = errors.New("a value is required")
+
+// builtin validators, named by the validate attribute of a field
+var validators = map[string]validator{
+	"package":   patternValidator("a valid Java package name", `[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*`),
+	"semver":    patternValidator("a valid semantic version", `(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-((0|[1-9]\d*|\d*[A-Za-z-][0-9A-Za-z-]*)(\.(0|[1-9]\d*|\d*[A-Za-z-][0-9A-Za-z-]*))*))?(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?`),
+	"dns-label": patternValidator("a valid DNS label", `[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?`),
+}
+
+// patternValidator accepts values matched in full by the regular expression
+func patternValidator(name, pattern string) validator {
+	re := regexp.MustCompile(`^(?:` + pattern + `)$`)
+	return validator{name: name, valid: re.MatchString}
+}
+
+// loadMetadata reads the descriptions of the template's fields from path.  A
+// template needn't describe its fields, so a missing file describes none.
+func loadMetadata(path string) (map[string]*fieldMeta, error) {
+	if !exists(path) {
+		return map[string]*fieldMeta{}, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := parseMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", metadataFile, err)
+	}
+	return meta, nil
+}
+
+// parseMetadata reads properties of the form field.attribute=value
+func parseMetadata(data []byte) (map[string]*fieldMeta, error) {
+	p, err := properties.Load(data, properties.UTF8)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := map[string]*fieldMeta{}
+	for _, key := range p.Keys() {
+		dot := strings.LastIndex(key, ".")
+		if dot <= 0 {
+			return nil, fmt.Errorf("%s: expected field.attribute e.g. %s.description", key, key)
+		}
+		field, attribute, value := key[:dot], key[dot+1:], p.GetString(key, "")
+
+		m, ok := meta[field]
+		if !ok {
+			m = &fieldMeta{}
+			meta[field] = m
+		}
+		if err := m.set(attribute, value); err != nil {
+			return nil, fmt.Errorf("%s: %s", key, err)
+		}
+	}
+	return meta, nil
+}
+
+// set sets an attribute of the field from its value in the metadata file
+func (m *fieldMeta) set(attribute, value string) error {
+	switch attribute {
+	case "description":
+		m.description = strings.TrimSpace(value)
+	case "choices":
+		for _, choice := range strings.Split(value, ",") {
+			if choice = strings.TrimSpace(choice); choice != "" {
+				m.choices = append(m.choices, choice)
+			}
+		}
+	case "validate":
+		for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+			v, ok := validators[name]
+			if !ok {
+				return fmt.Errorf("unknown validator %q; expected package, semver or dns-label", name)
+			}
+			m.validators = append(m.validators, v)
+		}
+	case "pattern":
+		if _, err := regexp.Compile(value); err != nil {
+			return err
+		}
+		m.validators = append(m.validators, patternValidator("matched by "+value, value))
+	case "required":
+		required, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", value)
+		}
+		m.required, m.optional = required, !required
+	default:
+		return fmt.Errorf("unknown attribute %q; expected description, choices, validate, pattern or required", attribute)
+	}
+	return nil
+}
+
+// unknownMetadata returns the sorted fields described by the metadata that
+// aren't properties of the template
+func unknownMetadata(meta map[string]*fieldMeta, p *properties.Properties) []string {
+	known := map[string]bool{}
+	for _, key := range p.Keys() {
+		known[key] = true
+	}
+
+	unknown := []string{}
+	for key := range meta {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// label returns the name the field is prompted for with, listing any choices
+func (m *fieldMeta) label(key string) string {
+	if m == nil || len(m.choices) == 0 {
+		return key
+	}
+	return fmt.Sprintf("%s (%s)", key, strings.Join(m.choices, "/"))
+}
+
+// check returns an error describing why value isn't acceptable for the field.
+// Empty values are only checked for being required: a field without metadata
+// may be left empty when prompted for, even once input runs out, but not with
+// --yes (interactive is false) since the template's author left no default.
+func (m *fieldMeta) check(value string, interactive bool) error {
+	if m == nil {
+		m = &fieldMeta{}
+	}
+
+	if strings.TrimSpace(value) == "" {
+		if m.required || (!interactive && !m.optional) {
+			return errRequired
+		}
+		return nil
+	}
+
+	if len(m.choices) > 0 && !contains(m.choices, value) {
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(m.choices, ", "))
+	}
+	for _, v := range m.validators {
+		if !v.valid(value) {
+			return fmt.Errorf("%q is not %s", value, v.name)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
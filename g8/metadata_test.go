@@ -0,0 +1,188 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/savaki/properties"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseMetadata(t *testing.T) {
+	Convey("Attributes are given as field.attribute", t, func() {
+		meta, err := parseMetadata([]byte(`name.description=Name of the service
+name.validate=dns-label
+database.choices=postgres, mysql , none
+organization.required=true
+version.validate=semver
+port.pattern=[0-9]+
+`))
+		So(err, ShouldBeNil)
+		So(meta["name"].description, ShouldEqual, "Name of the service")
+		So(meta["database"].choices, ShouldResemble, []string{"postgres", "mysql", "none"})
+		So(meta["database"].label("database"), ShouldEqual, "database (postgres/mysql/none)")
+		So(meta["organization"].required, ShouldBeTrue)
+		So(len(meta["version"].validators), ShouldEqual, 1)
+	})
+
+	Convey("Unknown attributes, validators and malformed values are errors", t, func() {
+		for _, text := range []string{
+			"name.descripton=typo",
+			"name.validate=email",
+			"name.required=maybe",
+			"name.pattern=[",
+			"name=missing attribute",
+		} {
+			_, err := parseMetadata([]byte(text))
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestFieldMetaCheck(t *testing.T) {
+	meta, err := parseMetadata([]byte(`package.validate=package
+version.validate=semver
+host.validate=dns-label
+database.choices=postgres, mysql
+port.pattern=[0-9]+
+organization.required=true
+description.required=false
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("Valid values are accepted", t, func() {
+		for key, value := range map[string]string{
+			"package":  "com.acme.orders",
+			"version":  "1.0.0-rc.1+build.5",
+			"host":     "order-service",
+			"database": "mysql",
+			"port":     "8080",
+		} {
+			So(meta[key].check(value, false), ShouldBeNil)
+		}
+	})
+
+	Convey("Invalid values are described", t, func() {
+		So(meta["package"].check("com.acme.2orders", false).Error(), ShouldEqual, `"com.acme.2orders" is not a valid Java package name`)
+		So(meta["version"].check("1.0", false).Error(), ShouldEqual, `"1.0" is not a valid semantic version`)
+		So(meta["host"].check("-orders", false).Error(), ShouldEqual, `"-orders" is not a valid DNS label`)
+		So(meta["database"].check("oracle", false).Error(), ShouldEqual, `"oracle" is not one of postgres, mysql`)
+		So(meta["port"].check("80a", false).Error(), ShouldEqual, `"80a" is not matched by [0-9]+`)
+	})
+
+	Convey("Required and optional fields", t, func() {
+		So(meta["organization"].check("", true), ShouldEqual, errRequired)
+		So(meta["description"].check("", false), ShouldBeNil)
+
+		// fields without metadata are only required when not prompted for
+		So(meta["name"].check("", true), ShouldBeNil)
+		So(meta["name"].check("", false), ShouldEqual, errRequired)
+	})
+}
+
+func TestCollectFieldsMetadata(t *testing.T) {
+	p, err := properties.Load([]byte(`name=orders
+database=postgres
+organization=
+`), properties.UTF8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := parseMetadata([]byte(`name.validate=dns-label
+database.choices=postgres, mysql
+organization.validate=package
+organization.required=true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// replies returns an ask function that gives each reply in turn, followed
+	// by io.EOF, recording the labels asked about
+	replies := func(asked *[]string, values ...string) func(string, string) (string, error) {
+		return func(label, defaultValue string) (string, error) {
+			*asked = append(*asked, label)
+			if len(values) == 0 {
+				return "", io.EOF
+			}
+			value := values[0]
+			values = values[1:]
+			return value, nil
+		}
+	}
+
+	Convey("Invalid answers are asked for again", t, func() {
+		asked := []string{}
+		fields, err := collectFields(p, meta, Options{}, nil, nil, replies(&asked, "Order Service", "", "oracle", "mysql", "", "com.acme"))
+		So(err, ShouldBeNil)
+		So(asked, ShouldResemble, []string{
+			"name", "name",
+			"database (postgres/mysql)", "database (postgres/mysql)",
+			"organization", "organization",
+		})
+		So(fields["name"], ShouldEqual, "orders")
+		So(fields["database"], ShouldEqual, "mysql")
+		So(fields["organization"], ShouldEqual, "com.acme")
+	})
+
+	Convey("Without input every violation is reported", t, func() {
+		opts := Options{NoInput: true, Fields: map[string]string{"name": "Order Service", "database": "oracle"}}
+		_, err := collectFields(p, meta, opts, nil, nil, nil)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `invalid answers:
+  name: "Order Service" is not a valid DNS label
+  database: "oracle" is not one of postgres, mysql
+  organization: no value given; set it with --organization=value`)
+	})
+
+	Convey("An empty value given for a required field is refused", t, func() {
+		opts := Options{NoInput: true, Fields: map[string]string{"name": "", "organization": ""}}
+		_, err := collectFields(p, meta, opts, nil, nil, nil)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "invalid answers:\n  organization: empty value not allowed")
+	})
+
+	Convey("Once input runs out the remaining fields are checked without asking", t, func() {
+		asked := []string{}
+		_, err := collectFields(p, meta, Options{}, nil, nil, replies(&asked, "billing"))
+		So(asked, ShouldResemble, []string{"name", "database (postgres/mysql)"})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "invalid answers:\n  organization: no value given; set it with --organization=value")
+	})
+	Convey("Piped input that runs out before a field with an empty default accepts it", t, func() {
+		p, err := properties.Load([]byte("name=orders\ndescription=\n"), properties.UTF8)
+		So(err, ShouldBeNil)
+
+		piped := &prompter{in: bufio.NewReader(strings.NewReader("billing\n")), out: &bytes.Buffer{}, fd: -1}
+		fields, err := collectFields(p, nil, Options{}, nil, nil, piped.ask)
+		So(err, ShouldBeNil)
+		So(fields["name"], ShouldEqual, "billing")
+		So(fields["description"], ShouldEqual, "")
+	})
+}
@@ -0,0 +1,330 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// errInterrupted is returned when the user presses Ctrl-C at a prompt
+var errInterrupted = errors.New("interrupted")
+
+// prompter asks for the value of each field a line at a time.  When input is a
+// terminal, the line can be edited with the arrow keys and earlier answers
+// recalled with up and down; otherwise lines are read as is, so answers can be
+// piped in.
+type prompter struct {
+	in      *bufio.Reader
+	out     io.Writer
+	fd      int      // file descriptor of the terminal; -1 when input isn't a terminal
+	history []string // answers given so far, oldest first
+	eof     bool
+}
+
+func newPrompter(in *os.File, out io.Writer) *prompter {
+	fd := int(in.Fd())
+	if !isTerminal(fd) {
+		fd = -1
+	}
+	return &prompter{in: bufio.NewReader(in), out: out, fd: fd}
+}
+
+// ask prompts for the value of key, returning the line entered; an empty line
+// accepts the default.  Once input runs out ask returns io.EOF.
+func (p *prompter) ask(key, defaultValue string) (string, error) {
+	prompt := fmt.Sprintf("%s [%s]: ", key, defaultValue)
+	fmt.Fprint(p.out, prompt)
+	if p.eof {
+		fmt.Fprintln(p.out)
+		return "", io.EOF
+	}
+
+	var line string
+	var err error
+	if p.fd >= 0 {
+		line, err = p.edit(prompt)
+	} else {
+		line, err = p.readLine()
+	}
+	if err == io.EOF {
+		p.eof = true
+		if line == "" {
+			return "", io.EOF
+		}
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(line) != "" {
+		p.history = append(p.history, line)
+	}
+	return line, nil
+}
+
+// readLine reads a line of input that isn't a terminal, echoing it so the
+// output reads the same as an interactive session
+func (p *prompter) readLine() (string, error) {
+	line, err := p.read()
+	fmt.Fprintln(p.out, line)
+	return line, err
+}
+
+// read reads a line without its line ending
+func (p *prompter) read() (string, error) {
+	line, err := p.in.ReadString('\n')
+	line = strings.TrimRight(line, "\r\n")
+	if err == io.EOF && line != "" {
+		// the last line needn't end with a newline
+		err = nil
+		p.eof = true
+	}
+	return line, err
+}
+
+// edit reads a line from the terminal with the terminal in raw mode.  Should
+// raw mode be unavailable, the terminal's own line editing is used.
+func (p *prompter) edit(prompt string) (string, error) {
+	state, err := makeRaw(p.fd)
+	if err != nil {
+		return p.read()
+	}
+	defer restore(p.fd, state)
+
+	e := newLineEditor(p.in, p.out, prompt, p.history)
+	return e.readLine()
+}
+
+// control keys understood by the line editor
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyCtrlK     = 11
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// lineEditor edits a single line of input on a terminal in raw mode, redrawing
+// the line after each key
+type lineEditor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	prompt  string
+	line    []rune
+	pos     int // cursor position within line
+	history []string
+	index   int    // entry of history being shown; len(history) for the new line
+	pending []rune // the new line, kept while browsing history
+}
+
+func newLineEditor(in *bufio.Reader, out io.Writer, prompt string, history []string) *lineEditor {
+	return &lineEditor{
+		in:      in,
+		out:     out,
+		prompt:  prompt,
+		history: history,
+		index:   len(history),
+	}
+}
+
+// readLine returns the line once enter is pressed.  Ctrl-C returns
+// errInterrupted and Ctrl-D on an empty line returns io.EOF.
+func (e *lineEditor) readLine() (string, error) {
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			fmt.Fprint(e.out, "\r\n")
+			return string(e.line), err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(e.line), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteRunes(e.pos, e.pos+1)
+		case keyBackspace, keyDelete:
+			e.deleteRunes(e.pos-1, e.pos)
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.line)
+		case keyCtrlB:
+			e.move(-1)
+		case keyCtrlF:
+			e.move(1)
+		case keyCtrlK:
+			e.deleteRunes(e.pos, len(e.line))
+		case keyCtrlU:
+			e.deleteRunes(0, e.pos)
+		case keyCtrlW:
+			e.deleteRunes(e.previousWord(), e.pos)
+		case keyCtrlP:
+			e.recall(-1)
+		case keyCtrlN:
+			e.recall(1)
+		case keyEscape:
+			if err := e.escape(); err != nil {
+				return "", err
+			}
+		default:
+			if unicode.IsControl(r) {
+				continue
+			}
+			e.insert(r)
+		}
+		e.refresh()
+	}
+}
+
+// escape handles the escape sequences sent by the arrow, home, end and delete
+// keys e.g. ESC [ D for left.  Unknown sequences are ignored.
+func (e *lineEditor) escape() error {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return err
+	}
+	if r != '[' && r != 'O' {
+		return nil
+	}
+
+	param := 0
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return err
+		}
+		if r < '0' || r > '9' {
+			break
+		}
+		param = param*10 + int(r-'0')
+	}
+
+	switch {
+	case r == 'A':
+		e.recall(-1)
+	case r == 'B':
+		e.recall(1)
+	case r == 'C':
+		e.move(1)
+	case r == 'D':
+		e.move(-1)
+	case r == 'H', r == '~' && (param == 1 || param == 7):
+		e.pos = 0
+	case r == 'F', r == '~' && (param == 4 || param == 8):
+		e.pos = len(e.line)
+	case r == '~' && param == 3:
+		e.deleteRunes(e.pos, e.pos+1)
+	}
+	return nil
+}
+
+func (e *lineEditor) insert(r rune) {
+	e.line = append(e.line, 0)
+	copy(e.line[e.pos+1:], e.line[e.pos:])
+	e.line[e.pos] = r
+	e.pos++
+}
+
+// deleteRunes removes line[from:to], clipped to the line
+func (e *lineEditor) deleteRunes(from, to int) {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(e.line) {
+		to = len(e.line)
+	}
+	if from >= to {
+		return
+	}
+	e.line = append(e.line[:from], e.line[to:]...)
+	e.pos = from
+}
+
+func (e *lineEditor) move(delta int) {
+	if pos := e.pos + delta; pos >= 0 && pos <= len(e.line) {
+		e.pos = pos
+	}
+}
+
+// previousWord returns the start of the word before the cursor
+func (e *lineEditor) previousWord() int {
+	pos := e.pos
+	for pos > 0 && unicode.IsSpace(e.line[pos-1]) {
+		pos--
+	}
+	for pos > 0 && !unicode.IsSpace(e.line[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// recall replaces the line with the previous (-1) or next (1) history entry
+func (e *lineEditor) recall(delta int) {
+	index := e.index + delta
+	if index < 0 || index > len(e.history) {
+		return
+	}
+	if e.index == len(e.history) {
+		e.pending = append([]rune(nil), e.line...)
+	}
+
+	e.index = index
+	if index == len(e.history) {
+		e.line = e.pending
+	} else {
+		e.line = []rune(e.history[index])
+	}
+	e.pos = len(e.line)
+}
+
+// refresh redraws the prompt and line, leaving the cursor in place
+func (e *lineEditor) refresh() {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, string(e.line))
+	if back := len(e.line) - e.pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
@@ -0,0 +1,131 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPrompter(t *testing.T) {
+	// piped returns a prompter reading input that isn't a terminal
+	piped := func(input string, out *bytes.Buffer) *prompter {
+		return &prompter{in: bufio.NewReader(strings.NewReader(input)), out: out, fd: -1}
+	}
+
+	Convey("Answers may contain spaces", t, func() {
+		out := &bytes.Buffer{}
+		p := piped("Order service for checkout\n\n", out)
+
+		value, err := p.ask("description", "a service")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "Order service for checkout")
+
+		value, err = p.ask("name", "orders")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "")
+		So(out.String(), ShouldEqual, "description [a service]: Order service for checkout\nname [orders]: \n")
+	})
+
+	Convey("Once input runs out ask returns io.EOF", t, func() {
+		out := &bytes.Buffer{}
+		p := piped("checkout\r\nlast line", out)
+
+		for _, expected := range []string{"checkout", "last line"} {
+			value, err := p.ask("name", "orders")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, expected)
+		}
+		So(p.history, ShouldResemble, []string{"checkout", "last line"})
+
+		_, err := p.ask("name", "orders")
+		So(err, ShouldEqual, io.EOF)
+		_, err = p.ask("name", "orders")
+		So(err, ShouldEqual, io.EOF)
+	})
+}
+
+func TestLineEditor(t *testing.T) {
+	// edit returns the line read by an editor fed the keys
+	edit := func(keys string, history ...string) (string, error) {
+		e := newLineEditor(bufio.NewReader(strings.NewReader(keys)), &bytes.Buffer{}, "name []: ", history)
+		return e.readLine()
+	}
+
+	Convey("Keys are inserted at the cursor", t, func() {
+		line, err := edit("abd\x1b[DX\x7fc\r")
+		So(err, ShouldBeNil)
+		So(line, ShouldEqual, "abcd")
+	})
+
+	Convey("Home, end, delete and the kill keys edit the line", t, func() {
+		line, _ := edit("world\x01hello \x05!\r")
+		So(line, ShouldEqual, "hello world!")
+
+		line, _ = edit("xhello\x1b[H\x1b[3~\r")
+		So(line, ShouldEqual, "hello")
+
+		line, _ = edit("order service\x17checkout\r")
+		So(line, ShouldEqual, "order checkout")
+
+		line, _ = edit("drop this\x15keep\r")
+		So(line, ShouldEqual, "keep")
+
+		line, _ = edit("keep drop\x1b[D\x1b[D\x1b[D\x1b[D\x0b\r")
+		So(line, ShouldEqual, "keep ")
+	})
+
+	Convey("Up and down browse earlier answers", t, func() {
+		line, _ := edit("new\x1b[A\x1b[A\r", "first", "second")
+		So(line, ShouldEqual, "first")
+
+		line, _ = edit("new\x1b[A\x1b[A\x1b[A\x1b[B\x1b[B\r", "first", "second")
+		So(line, ShouldEqual, "new")
+
+		line, _ = edit("\x10 answer\r", "first")
+		So(line, ShouldEqual, "first answer")
+	})
+
+	Convey("Ctrl-C interrupts and Ctrl-D on an empty line ends input", t, func() {
+		_, err := edit("abc\x03")
+		So(err, ShouldEqual, errInterrupted)
+
+		_, err = edit("\x04")
+		So(err, ShouldEqual, io.EOF)
+
+		line, err := edit("ab\x02\x04\r")
+		So(err, ShouldBeNil)
+		So(line, ShouldEqual, "a")
+	})
+
+	Convey("The line is redrawn with the cursor in place", t, func() {
+		out := &bytes.Buffer{}
+		e := newLineEditor(bufio.NewReader(strings.NewReader("ab\x1b[D\r")), out, "name []: ", nil)
+		e.readLine()
+		So(out.String(), ShouldEqual, "\rname []: a\x1b[K\rname []: ab\x1b[K\rname []: ab\x1b[K\x1b[1D\r\n")
+	})
+}
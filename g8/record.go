@@ -0,0 +1,92 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/savaki/go-giter8/git"
+	"github.com/savaki/properties"
+	"io"
+	"path/filepath"
+)
+
+// name of the generation record written into the root of the generated project
+const recordFile = ".g8.json"
+
+// record describes how a project was generated, so it can later be
+// regenerated, audited or upgraded.  A record can itself be read with
+// --answers to regenerate the project.
+type record struct {
+	Template  string            `json:"template,omitempty"` // URL the template was cloned from
+	Repo      string            `json:"repo"`               // template repo as given e.g. loyal3/service-template-finatra.g8
+	Commit    string            `json:"commit,omitempty"`   // SHA of the template commit used
+	Version   string            `json:"version"`            // version of g8 that generated the project
+	Generated string            `json:"generated"`          // RFC 3339 time of generation
+	Answers   map[string]string `json:"answers"`            // answers to the template's fields
+}
+
+// newRecord describes the generation of the template in dir from the answers
+// in fields.  Only the properties declared in p are recorded, less those that
+// configure the template, so built in fields and answers that aren't fields of
+// the template are left out.  Any seed is recorded with the answers so random
+// values can be reproduced.
+func newRecord(client *git.Git, dir string, p *properties.Properties, opts Options, fields, builtins map[string]string) record {
+	answers := map[string]string{}
+	for _, key := range p.Keys() {
+		if value, ok := fields[key]; ok && !isConfig(key) {
+			answers[key] = value
+		}
+	}
+	if opts.Seed != "" {
+		answers[answerSeed] = opts.Seed
+	}
+
+	r := record{
+		Repo:      opts.Repo,
+		Version:   version,
+		Generated: builtins["now"],
+		Answers:   answers,
+	}
+	if url, err := client.Remote(dir); err == nil {
+		r.Template = url
+	}
+	if commit, err := client.Head(dir); err == nil {
+		r.Commit = commit
+	}
+	return r
+}
+
+// write writes the record as indented JSON to w
+func (r record) write(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// writeRecord writes the record into the root of the project in target
+func writeRecord(target string, r record) error {
+	return createFile(filepath.Join(target, recordFile), 0644, r.write)
+}
@@ -0,0 +1,109 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"github.com/savaki/go-giter8/git"
+	"github.com/savaki/properties"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	builtins := map[string]string{"year": "2015", "now": "2015-03-14T09:26:53Z", "user": "matt"}
+	fields := map[string]string{
+		"year":        "2015",
+		"now":         "2015-03-14T09:26:53Z",
+		"user":        "someone-else",
+		"name":        "My Service",
+		"verbatim":    "*.sh",
+		"description": "a service",
+		"bogus":       "not a field",
+	}
+	p, err := properties.Load([]byte("name=My Service\ndescription=\nverbatim=*.sh\n"), properties.UTF8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Repo: "loyal3/service-template-finatra.g8", Seed: "golden"}
+
+	Convey("The record holds the answers to the template's properties, but not built in fields, configuration or unknown answers", t, func() {
+		r := newRecord(git.New("/nonexistent/git", ""), "", p, opts, fields, builtins)
+		So(r.Repo, ShouldEqual, "loyal3/service-template-finatra.g8")
+		So(r.Version, ShouldEqual, version)
+		So(r.Generated, ShouldEqual, "2015-03-14T09:26:53Z")
+		So(r.Commit, ShouldEqual, "")
+		So(r.Answers, ShouldResemble, map[string]string{
+			"name":        "My Service",
+			"description": "a service",
+			answerSeed:    "golden",
+		})
+	})
+
+	Convey("The record can be read back as answers", t, func() {
+		dir, err := ioutil.TempDir("", "record")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		r := newRecord(git.New("/nonexistent/git", ""), "", p, opts, fields, builtins)
+		So(writeRecord(dir, r), ShouldBeNil)
+
+		answers, err := loadAnswers(filepath.Join(dir, recordFile))
+		So(err, ShouldBeNil)
+		So(answers, ShouldResemble, r.Answers)
+
+		replayed := Options{}
+		replayed.setAnswers(answers)
+		So(replayed.Seed, ShouldEqual, "golden")
+	})
+
+	gitpath, err := exec.LookPath("git")
+	if err != nil {
+		return
+	}
+
+	Convey("The template URL and commit come from the clone", t, func() {
+		dir, err := ioutil.TempDir("", "template")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		run := func(args ...string) string {
+			cmd := exec.Command(gitpath, args...)
+			cmd.Dir = dir
+			output, err := cmd.Output()
+			So(err, ShouldBeNil)
+			return string(output)
+		}
+		run("init", "-q")
+		run("remote", "add", "origin", "https://github.com/loyal3/service-template-finatra.g8.git")
+		run("-c", "user.name=g8", "-c", "user.email=g8@example.com", "commit", "-q", "--allow-empty", "-m", "initial")
+		head := run("rev-parse", "HEAD")
+
+		r := newRecord(git.New(gitpath, ""), dir, p, opts, fields, builtins)
+		So(r.Template, ShouldEqual, "https://github.com/loyal3/service-template-finatra.g8.git")
+		So(r.Commit+"\n", ShouldEqual, head)
+	})
+}
@@ -20,23 +20,15 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
 // SOFTWARE.
 
-package template
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
 
-import (
-	. "github.com/smartystreets/goconvey/convey"
-	"testing"
-)
-
-func TestGiter8ify(t *testing.T) {
-	Convey("Given a string with escaped $", t, func() {
-		text := []byte(`-> \$ <-`)
+package main
 
-		Convey("When I invoke #giter8ify", func() {
-			result := giter8ify(text)
+import "syscall"
 
-			Convey("Then I expect the escaped $ to be unescaped", func() {
-				So(string(result), ShouldEqual, `-> $ <-`)
-			})
-		})
-	})
-}
+// ioctl requests reading and writing the terminal attributes
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
@@ -20,16 +20,12 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
 // SOFTWARE.
 
-package template
+package main
 
-import (
-	"regexp"
-)
+import "syscall"
 
-var (
-	stringEscapeRe = regexp.MustCompile(`\\\$`)
+// ioctl requests reading and writing the terminal attributes
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
 )
-
-func giter8ify(text []byte) []byte {
-	return stringEscapeRe.ReplaceAll(text, []byte(`$`))
-}
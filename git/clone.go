@@ -27,6 +27,7 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"strings"
 )
 
 func (g *Git) Clone(url string) error {
@@ -54,6 +55,33 @@ func (g *Git) Export(repo string) error {
 	return err
 }
 
+// Config returns the value of a git configuration key e.g. user.name, or an
+// error if the key is not set
+func (g *Git) Config(key string) (string, error) {
+	return g.output("", "config", "--get", key)
+}
+
+// Head returns the SHA of the commit checked out in the repository at dir
+func (g *Git) Head(dir string) (string, error) {
+	return g.output(dir, "rev-parse", "HEAD")
+}
+
+// Remote returns the URL the repository at dir was cloned from
+func (g *Git) Remote(dir string) (string, error) {
+	return g.output(dir, "config", "--get", "remote.origin.url")
+}
+
+// output runs git in dir and returns its trimmed output
+func (g *Git) output(dir string, args ...string) (string, error) {
+	cmd := exec.Command(g.Git, args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 func https(repo string) string {
 	return fmt.Sprintf("https://github.com/%s.git", repo)
 }
@@ -0,0 +1,133 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// longest expression quoted in an error message
+const maxExprLen = 60
+
+// Error describes a problem with a template, located in terms of the original
+// giter8 source rather than anything it is transformed into.
+type Error struct {
+	Name       string // name of the template, typically the file it was read from
+	Line, Col  int    // 1-based position of the problem
+	Expr       string // the offending $...$ expression, if any
+	Message    string
+	Suggestion string // a likely intended name, if any
+}
+
+func (e *Error) Error() string {
+	text := fmt.Sprintf("template: %s:%d:%d: %s", e.Name, e.Line, e.Col, e.Message)
+	if e.Expr != "" {
+		text += " in " + e.Expr
+	}
+	if e.Suggestion != "" {
+		text += fmt.Sprintf("; did you mean %q?", e.Suggestion)
+	}
+	return text
+}
+
+// exprAt returns the expression that starts with the $ at pos.  Expressions
+// that are never closed are cut off at the end of the line.
+func exprAt(text []byte, pos int) string {
+	if pos < 0 || pos >= len(text) || text[pos] != delim {
+		return ""
+	}
+
+	end := len(text)
+	if i := bytes.IndexAny(text[pos+1:], "$\n"); i >= 0 {
+		end = pos + 1 + i
+		if text[end] == delim {
+			end++
+		}
+	}
+
+	expr := text[pos:end]
+	if utf8.RuneCount(expr) > maxExprLen {
+		return string([]rune(string(expr))[:maxExprLen]) + "..."
+	}
+	return string(expr)
+}
+
+// suggest returns the candidate closest to name, provided it is close enough
+// to plausibly be a typo
+func suggest(name string, candidates []string) string {
+	sort.Strings(candidates)
+
+	best, bestDistance := "", -1
+	for _, candidate := range candidates {
+		d := distance(name, candidate)
+		if bestDistance < 0 || d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+
+	limit := utf8.RuneCountInString(name) / 3
+	if limit < 2 {
+		limit = 2
+	}
+	if bestDistance < 0 || bestDistance > limit {
+		return ""
+	}
+	return best
+}
+
+// distance computes the Levenshtein distance between a and b
+func distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(t)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
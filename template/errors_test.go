@@ -0,0 +1,87 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	Convey("An unknown formatter suggests the closest known formatter", t, func() {
+		_, err := New("src/main/g8/build.sbt").Parse([]byte("name := \"$name;format=\"norm,uper\"$\""))
+		So(err, ShouldNotBeNil)
+
+		e, ok := err.(*Error)
+		So(ok, ShouldBeTrue)
+		So(e.Name, ShouldEqual, "src/main/g8/build.sbt")
+		So(e.Line, ShouldEqual, 1)
+		So(e.Col, ShouldEqual, 29)
+		So(e.Expr, ShouldEqual, `$name;format="norm,uper"$`)
+		So(e.Suggestion, ShouldEqual, "upper")
+		So(err.Error(), ShouldEqual, `template: src/main/g8/build.sbt:1:29: unknown formatter "uper" in $name;format="norm,uper"$; did you mean "upper"?`)
+	})
+
+	Convey("Nothing is suggested when no formatter is close", t, func() {
+		_, err := Parse([]byte("$name__zzzzzzzz$"))
+		So(err, ShouldNotBeNil)
+		So(err.(*Error).Suggestion, ShouldEqual, "")
+	})
+
+	Convey("An unterminated string quotes the rest of the expression", t, func() {
+		_, err := Parse([]byte("a\nprice: $name;format=\"upper$\nb"))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "template: template:2:21: unterminated quoted string in $name;format=\"upper$")
+	})
+}
+
+func TestExprAt(t *testing.T) {
+	Convey("#exprAt returns the expression starting at the position", t, func() {
+		text := []byte(`a $name__upper$ b`)
+		So(exprAt(text, 2), ShouldEqual, "$name__upper$")
+		So(exprAt(text, 0), ShouldEqual, "")
+		So(exprAt(text, 100), ShouldEqual, "")
+	})
+
+	Convey("#exprAt truncates long expressions", t, func() {
+		text := []byte("$" + strings.Repeat("x", 100))
+		So(exprAt(text, 0), ShouldEqual, "$"+strings.Repeat("x", maxExprLen-1)+"...")
+	})
+}
+
+func TestSuggest(t *testing.T) {
+	Convey("#suggest finds likely typos", t, func() {
+		candidates := []string{"name", "organization", "version"}
+		So(suggest("nmae", candidates), ShouldEqual, "name")
+		So(suggest("orgnization", candidates), ShouldEqual, "organization")
+		So(suggest("description", candidates), ShouldEqual, "")
+		So(suggest("name", nil), ShouldEqual, "")
+	})
+
+	Convey("#distance computes the Levenshtein distance", t, func() {
+		So(distance("", "abc"), ShouldEqual, 3)
+		So(distance("kitten", "sitting"), ShouldEqual, 3)
+		So(distance("émile", "emile"), ShouldEqual, 1)
+	})
+}
@@ -23,41 +23,88 @@
 package template
 
 import (
-	"code.google.com/p/go-uuid/uuid"
+	"bytes"
+	"fmt"
 	"regexp"
 	"strings"
-	text_template "text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
-var funcMap = text_template.FuncMap{
+// builtins holds the standard giter8 formatters, keyed by both their short and
+// long names
+var builtins = FuncMap{
 	"upper":           Upper,
 	"uppercase":       Upper,
 	"lower":           Lower,
 	"lowercase":       Lower,
+	"cap":             Capitalize,
+	"capitalize":      Capitalize,
+	"decap":           Decapitalize,
+	"decapitalize":    Decapitalize,
 	"start":           Start,
+	"start-case":      Start,
 	"word":            Word,
 	"word-only":       Word,
-	"camel":           CamelLower,
+	"space":           WordSpace,
+	"word-space":      WordSpace,
 	"Camel":           Camel,
-	"cap":             Capitalize,
-	"capitalize":      Capitalize,
+	"upper-camel":     Camel,
+	"camel":           CamelLower,
+	"lower-camel":     CamelLower,
 	"hyphen":          Hyphenate,
 	"hyphenate":       Hyphenate,
-	"normalize":       Normalize,
 	"norm":            Normalize,
+	"normalize":       Normalize,
 	"snake":           Snake,
 	"snake-case":      Snake,
+	"dotReverse":      DotReverse,
+	"dot-reverse":     DotReverse,
+	"package":         Package,
+	"package-naming":  Package,
 	"packaged":        Packaged,
-	"packaged-case":   Packaged,
+	"package-dir":     Packaged,
+	"packaged-case":   Packaged, // retained for templates written for earlier versions of go-giter8
 	"random":          Random,
 	"generate-random": Random,
+
+	// random values and hashes, see RandomFuncs
+	"uuid":   UUID,
+	"hex":    Hex,
+	"sha256": SHA256,
+	"hash":   Hash,
+
+	// word based case conversions, see SplitWords
+	"kebab":         Kebab,
+	"kebab-case":    Kebab,
+	"constant":      Constant,
+	"constant-case": Constant,
+	"dot":           DotCase,
+	"dot-case":      DotCase,
+	"path":          PathCase,
+	"path-case":     PathCase,
+	"title":         Title,
+	"title-case":    Title,
+	"exported":      GoExported,
+	"go-exported":   GoExported,
+
+	// formatters that take arguments e.g. $name;format="truncate(20)"$
+	"truncate": Truncate,
+	"replace":  Replace,
+	"padLeft":  PadLeft,
+	"padRight": PadRight,
+	"default":  Default,
+	"date":     Date,
 }
 
 var (
-	wordRe       = regexp.MustCompile(`\W`)
-	whitespaceRe = regexp.MustCompile(`\s`)
+	// word characters are letters, combining marks, digits and underscores in
+	// any script, not just a-zA-Z0-9_
+	wordRe       = regexp.MustCompile(`[^\p{L}\p{M}\p{N}_]`)
+	whitespaceRe = regexp.MustCompile(`[\s\p{Z}]+`)
 	dotRe        = regexp.MustCompile(`\.`)
-	snakeRe      = regexp.MustCompile(`\.|\s`)
+	snakeRe      = regexp.MustCompile(`[\s\p{Z}.\-]+`)
 )
 
 func Upper(value string) string {
@@ -68,41 +115,54 @@ func Lower(value string) string {
 	return strings.ToLower(value)
 }
 
+// Word removes all non-word characters i.e. anything other than letters,
+// digits and underscores
 func Word(value string) string {
 	return wordRe.ReplaceAllString(value, "")
 }
 
+// WordSpace replaces all non-word characters with a space
+func WordSpace(value string) string {
+	return wordRe.ReplaceAllString(value, " ")
+}
+
+// Capitalize converts the first character to title case e.g. émile -> Émile.
+// Combining marks that follow the first rune are left in place.
 func Capitalize(value string) string {
-	switch len(value) {
-	case 0:
-		return ""
-	case 1:
-		return strings.ToUpper(value[:1])
-	default:
-		return strings.ToUpper(value[:1]) + value[1:]
-	}
+	return mapFirst(value, unicode.ToTitle)
 }
 
+// Decapitalize converts the first character to lower case
 func Decapitalize(value string) string {
-	switch len(value) {
-	case 0:
-		return ""
-	case 1:
-		return strings.ToLower(value[:1])
-	default:
-		return strings.ToLower(value[:1]) + value[1:]
+	return mapFirst(value, unicode.ToLower)
+}
+
+// mapFirst applies mapping to the first rune of value, leaving invalid UTF-8
+// untouched
+func mapFirst(value string, mapping func(rune) rune) string {
+	r, size := utf8.DecodeRuneInString(value)
+	if r == utf8.RuneError && size <= 1 {
+		return value
 	}
+	return string(mapping(r)) + value[size:]
 }
 
+// Start lowercases the value and then capitalizes each whitespace separated
+// word
 func Start(value string) string {
-	parts := strings.Split(value, " ")
-	capped := []string{}
+	value = strings.ToLower(value)
 
-	for _, part := range parts {
-		capped = append(capped, Capitalize(part))
+	buffer := bytes.NewBuffer(make([]byte, 0, len(value)))
+	boundary := true
+	for _, r := range value {
+		if boundary {
+			r = unicode.ToTitle(r)
+		}
+		buffer.WriteRune(r)
+		boundary = unicode.IsSpace(r)
 	}
 
-	return strings.Join(capped, " ")
+	return buffer.String()
 }
 
 func Camel(value string) string {
@@ -116,22 +176,101 @@ func CamelLower(value string) string {
 }
 
 func Normalize(value string) string {
-	value = Hyphenate(value)
-	return strings.ToLower(value)
+	value = strings.ToLower(value)
+	return Hyphenate(value)
 }
 
+// Hyphenate replaces each run of whitespace with a single hyphen
 func Hyphenate(value string) string {
 	return whitespaceRe.ReplaceAllString(value, "-")
 }
 
+// DotReverse reverses the dot separated segments e.g. example.com -> com.example
+func DotReverse(value string) string {
+	parts := strings.Split(value, ".")
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, ".")
+}
+
+// Package replaces each run of whitespace with a dot
+func Package(value string) string {
+	return whitespaceRe.ReplaceAllString(value, ".")
+}
+
+// Packaged replaces dots with slashes e.g. net.databinder -> net/databinder
 func Packaged(value string) string {
 	return dotRe.ReplaceAllString(value, "/")
 }
 
+// Snake replaces each run of whitespace, dots and hyphens with an underscore
 func Snake(value string) string {
 	return snakeRe.ReplaceAllString(value, "_")
 }
 
-func Random(value string) string {
-	return value + Word(uuid.NewRandom().String())
+// Truncate shortens value to at most n characters
+func Truncate(value string, n int) (string, error) {
+	if n < 0 {
+		return "", fmt.Errorf("length must not be negative")
+	}
+	runes := []rune(value)
+	if len(runes) <= n {
+		return value, nil
+	}
+	return string(runes[:n]), nil
+}
+
+// Replace replaces every occurrence of old with new
+func Replace(value, old, new string) string {
+	return strings.Replace(value, old, new, -1)
+}
+
+// PadLeft pads the start of value with pad until it is n characters long
+// e.g. padLeft(4,"0") turns 7 into 0007
+func PadLeft(value string, n int, pad string) (string, error) {
+	padding, err := padding(value, n, pad)
+	return padding + value, err
+}
+
+// PadRight pads the end of value with pad until it is n characters long
+func PadRight(value string, n int, pad string) (string, error) {
+	padding, err := padding(value, n, pad)
+	return value + padding, err
+}
+
+func padding(value string, n int, pad string) (string, error) {
+	if pad == "" {
+		return "", fmt.Errorf("padding must not be empty")
+	}
+
+	missing := n - utf8.RuneCountInString(value)
+	if missing <= 0 {
+		return "", nil
+	}
+	padRunes := []rune(pad)
+	result := make([]rune, 0, missing)
+	for i := 0; i < missing; i++ {
+		result = append(result, padRunes[i%len(padRunes)])
+	}
+	return string(result), nil
+}
+
+// Default returns fallback when value is empty.  A field with the default
+// formatter is never considered undefined.
+func Default(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// Date reformats value, an RFC 3339 timestamp such as the built in now field,
+// using a Go time layout e.g. $now;format="date:2006-01-02"$
+func Date(value, layout string) (string, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", fmt.Errorf("%q is not an RFC 3339 timestamp", value)
+	}
+	return t.Format(layout), nil
 }
@@ -69,13 +69,6 @@ func TestPackaged(t *testing.T) {
 	})
 }
 
-func TestRandom(t *testing.T) {
-	Convey("When I #Random a string", t, func() {
-		result := Random("hello")
-		So(len(result), ShouldBeGreaterThan, len("hello"))
-	})
-}
-
 func TestUpper(t *testing.T) {
 	Convey("When I #Upper a string", t, func() {
 		result := Upper("hello world")
@@ -128,3 +121,148 @@ func TestSnake(t *testing.T) {
 		So(result, ShouldEqual, "hello_world_argle_bargle")
 	})
 }
+
+// formatterCase is the expected output of a formatter, by every one of its names
+type formatterCase struct {
+	names    []string
+	input    string
+	expected string
+}
+
+// conformance follows the definitions of the upstream giter8 formatters
+var conformance = []formatterCase{
+	{[]string{"upper", "uppercase"}, "Hello World", "HELLO WORLD"},
+	{[]string{"lower", "lowercase"}, "Hello World", "hello world"},
+	{[]string{"cap", "capitalize"}, "hello world", "Hello world"},
+	{[]string{"decap", "decapitalize"}, "Hello World", "hello World"},
+	{[]string{"start", "start-case"}, "hello WORLD", "Hello World"},
+	{[]string{"word", "word-only"}, "hello-world_2.x!", "helloworld_2x"},
+	{[]string{"space", "word-space"}, "hello-world.x", "hello world x"},
+	{[]string{"Camel", "upper-camel"}, "hello big-world", "HelloBigworld"},
+	{[]string{"camel", "lower-camel"}, "Hello big World", "helloBigWorld"},
+	{[]string{"hyphen", "hyphenate"}, "hello  big\tworld", "hello-big-world"},
+	{[]string{"norm", "normalize"}, "Hello  Big World", "hello-big-world"},
+	{[]string{"snake", "snake-case"}, "hello big-world.x", "hello_big_world_x"},
+	{[]string{"dotReverse", "dot-reverse"}, "databinder.net", "net.databinder"},
+	{[]string{"package", "package-naming"}, "net databinder  app", "net.databinder.app"},
+	{[]string{"packaged", "package-dir", "packaged-case"}, "net.databinder", "net/databinder"},
+}
+
+func TestFormatterConformance(t *testing.T) {
+	Convey("Every formatter name is registered and formats as expected", t, func() {
+		// random values, hashes and formatters taking arguments are covered separately
+		tested := map[string]bool{"random": true, "generate-random": true, "uuid": true, "hex": true, "sha256": true, "hash": true}
+		for _, name := range []string{"truncate", "replace", "padLeft", "padRight", "default", "date"} {
+			tested[name] = true
+		}
+		for _, c := range append(conformance, wordConformance...) {
+			for _, name := range c.names {
+				formatter, ok := Lookup(name)
+				So(ok, ShouldBeTrue)
+				So(formatter.(func(string) string)(c.input), ShouldEqual, c.expected)
+				tested[name] = true
+			}
+		}
+
+		for name := range builtins {
+			So(tested[name], ShouldBeTrue)
+		}
+	})
+}
+
+func TestUnicode(t *testing.T) {
+	Convey("Accented names are handled a character at a time", t, func() {
+		So(Capitalize("émile service"), ShouldEqual, "Émile service")
+		So(Decapitalize("Émile"), ShouldEqual, "émile")
+		So(Capitalize("é"), ShouldEqual, "É")
+		So(Start("émile ÇA va"), ShouldEqual, "Émile Ça Va")
+		So(Camel("émile service"), ShouldEqual, "ÉmileService")
+		So(CamelLower("Émile Service"), ShouldEqual, "émileService")
+		So(Word("crème-brûlée!"), ShouldEqual, "crèmebrûlée")
+		So(WordSpace("crème-brûlée"), ShouldEqual, "crème brûlée")
+		So(Normalize("Crème Brûlée"), ShouldEqual, "crème-brûlée")
+	})
+
+	Convey("Combining marks stay attached to their letter", t, func() {
+		decomposed := "e\u0301mile"
+		So(Capitalize(decomposed), ShouldEqual, "E\u0301mile")
+		So(Word(decomposed), ShouldEqual, decomposed)
+	})
+
+	Convey("Digraphs are converted to title case rather than upper case", t, func() {
+		So(Capitalize("ǆungla"), ShouldEqual, "ǅungla")
+	})
+
+	Convey("Turkish dotless and dotted i are case mapped without a locale", t, func() {
+		So(Upper("ıi"), ShouldEqual, "II")
+		So(Capitalize("ılık"), ShouldEqual, "Ilık")
+		So(Decapitalize("İstanbul"), ShouldEqual, "istanbul")
+		So(Start("ılık su"), ShouldEqual, "Ilık Su")
+	})
+
+	Convey("CJK names, which have no case, are preserved", t, func() {
+		So(Capitalize("服务"), ShouldEqual, "服务")
+		So(Start("订单 服务"), ShouldEqual, "订单 服务")
+		So(Camel("订单 服务"), ShouldEqual, "订单服务")
+		So(Word("订单・服务"), ShouldEqual, "订单服务")
+		So(Hyphenate("订单　服务"), ShouldEqual, "订单-服务")
+		So(Snake("注文 サービス"), ShouldEqual, "注文_サービス")
+	})
+
+	Convey("Invalid UTF-8 is not made worse", t, func() {
+		So(Capitalize("\xffabc"), ShouldEqual, "\xffabc")
+		So(Decapitalize(""), ShouldEqual, "")
+	})
+}
+
+func TestArgumentFormatters(t *testing.T) {
+	Convey("#Truncate keeps at most n characters", t, func() {
+		value, err := Truncate("émile service", 5)
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "émile")
+
+		value, err = Truncate("abc", 5)
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "abc")
+
+		_, err = Truncate("abc", -1)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("#Replace replaces every occurrence", t, func() {
+		So(Replace("my-cool-service", "-", "_"), ShouldEqual, "my_cool_service")
+	})
+
+	Convey("#PadLeft and #PadRight pad to the given width", t, func() {
+		value, err := PadLeft("7", 4, "0")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "0007")
+
+		value, err = PadRight("ab", 7, "-=")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "ab-=-=-")
+
+		value, err = PadLeft("12345", 4, "0")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "12345")
+
+		_, err = PadLeft("7", 4, "")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("#Default replaces empty values", t, func() {
+		So(Default("", "none"), ShouldEqual, "none")
+		So(Default("some", "none"), ShouldEqual, "some")
+	})
+}
+
+func TestDate(t *testing.T) {
+	Convey("#Date reformats RFC 3339 timestamps", t, func() {
+		value, err := Date("2026-03-07T09:05:00Z", "2006-01-02 15:04")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "2026-03-07 09:05")
+
+		_, err = Date("yesterday", "2006")
+		So(err, ShouldNotBeNil)
+	})
+}
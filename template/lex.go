@@ -0,0 +1,331 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// itemType identifies the type of lex items.
+type itemType int
+
+const (
+	itemError      itemType = iota // error occurred; val is the text of the error
+	itemEOF                        // end of the input
+	itemText                       // literal text with \$ escapes already resolved
+	itemLeftDelim                  // $ opening an expression
+	itemRightDelim                 // $ closing an expression
+	itemIdentifier                 // field, formatter or keyword name
+	itemSeparator                  // __ between a field and its short formatters
+	itemSemicolon                  // ; introducing an option e.g. format="..."
+	itemAssign                     // =
+	itemString                     // quoted string, quotes included
+	itemLeftParen                  // (
+	itemRightParen                 // )
+	itemComma                      // ,
+	itemDot                        // .
+	itemNot                        // !
+)
+
+const (
+	delim  = '$'
+	escape = '\\'
+	eof    = -1
+)
+
+// item represents a token returned from the lexer.
+type item struct {
+	typ itemType
+	pos int // byte offset of the item in the original input
+	val string
+}
+
+func (i item) String() string {
+	switch {
+	case i.typ == itemEOF:
+		return "EOF"
+	case i.typ == itemError:
+		return i.val
+	case len(i.val) > 10 && i.typ == itemText:
+		return fmt.Sprintf("%.10q...", i.val)
+	}
+	return fmt.Sprintf("%q", i.val)
+}
+
+// stateFn represents the state of the lexer as a function returning the next state.
+type stateFn func(*lexer) stateFn
+
+// lexer holds the state of the scanner.  Unlike text/template the lexer runs to
+// completion up front; giter8 templates are small and the parser wants to look
+// ahead freely.
+type lexer struct {
+	input []byte
+	start int    // start position of the current item
+	pos   int    // current position in the input
+	width int    // width of the last rune read
+	text  []byte // pending literal text, with escapes resolved
+	open  int    // position of the $ that opened the current expression
+	items []item
+}
+
+// lex scans the giter8 template into a slice of items that always ends with
+// either itemEOF or itemError.
+func lex(input []byte) []item {
+	l := &lexer{input: input}
+	for state := lexText; state != nil; {
+		state = state(l)
+	}
+	return l.items
+}
+
+func (l *lexer) next() rune {
+	if l.pos >= len(l.input) {
+		l.width = 0
+		return eof
+	}
+	r, w := utf8.DecodeRune(l.input[l.pos:])
+	l.width = w
+	l.pos += w
+	return r
+}
+
+func (l *lexer) peek() rune {
+	r := l.next()
+	l.backup()
+	return r
+}
+
+func (l *lexer) backup() {
+	l.pos -= l.width
+}
+
+func (l *lexer) emit(t itemType) {
+	l.items = append(l.items, item{t, l.start, string(l.input[l.start:l.pos])})
+	l.start = l.pos
+}
+
+func (l *lexer) ignore() {
+	l.start = l.pos
+}
+
+// errorf emits an error item at the given position and terminates the scan.
+func (l *lexer) errorf(pos int, format string, args ...interface{}) stateFn {
+	l.items = append(l.items, item{itemError, pos, fmt.Sprintf(format, args...)})
+	return nil
+}
+
+// flushText emits any literal text accumulated so far.  Text is accumulated
+// rather than sliced from the input so that \$ can be collapsed to $.
+func (l *lexer) flushText(start int) {
+	if len(l.text) > 0 {
+		l.items = append(l.items, item{itemText, start, string(l.text)})
+		l.text = l.text[:0]
+	}
+}
+
+// lexText scans literal text until an unescaped $.
+func lexText(l *lexer) stateFn {
+	start := l.start
+	for i := l.pos; i < len(l.input); i++ {
+		switch l.input[i] {
+		case escape:
+			if i+1 < len(l.input) && l.input[i+1] == delim {
+				l.text = append(l.text, l.input[l.pos:i]...)
+				l.text = append(l.text, delim)
+				i++
+				l.pos = i + 1
+			}
+		case delim:
+			if !startsExpr(l.input, i) {
+				// a $ of some other language e.g. {{ $x := 1 }}, ${x} or $HOME
+				continue
+			}
+			l.text = append(l.text, l.input[l.pos:i]...)
+			l.flushText(start)
+			l.pos = i
+			l.start = i
+			return lexLeftDelim
+		}
+	}
+	l.text = append(l.text, l.input[l.pos:]...)
+	l.flushText(start)
+	l.pos = len(l.input)
+	l.start = l.pos
+	l.emit(itemEOF)
+	return nil
+}
+
+// startsExpr reports whether the $ at input[pos] opens a giter8 expression:
+// $name$ or $name__formatter$, $name;format="..."$, or a name followed by
+// arguments such as $if(...)$ or $include(...)$, with a closing $ on the same
+// line.  Any other $, as used by shell scripts, Helm charts, string
+// interpolation and prose, is literal text.
+func startsExpr(input []byte, pos int) bool {
+	end := pos + 1
+	if end >= len(input) || !isNameStart(rune(input[end])) {
+		return false
+	}
+	for end < len(input) && isIdentRune(rune(input[end])) {
+		end++
+	}
+
+	rest := input[end:]
+	if newline := bytes.IndexByte(rest, '\n'); newline >= 0 {
+		rest = rest[:newline]
+	}
+	switch {
+	case bytes.HasPrefix(rest, []byte{delim}):
+		return true
+	case bytes.HasPrefix(rest, []byte("(")):
+	case bytes.HasPrefix(rest, []byte(";")):
+		option := bytes.TrimLeft(rest[1:], " \t")
+		if !bytes.HasPrefix(option, []byte("format")) || !bytes.HasPrefix(bytes.TrimLeft(option[len("format"):], " \t"), []byte("=")) {
+			return false
+		}
+	default:
+		return false
+	}
+	return bytes.IndexByte(rest[1:], delim) >= 0
+}
+
+// lexLeftDelim scans the $ that opens an expression.
+func lexLeftDelim(l *lexer) stateFn {
+	l.open = l.pos
+	l.pos++
+	l.emit(itemLeftDelim)
+	return lexInsideExpr
+}
+
+// lexInsideExpr scans the elements inside an expression.
+func lexInsideExpr(l *lexer) stateFn {
+	switch r := l.next(); {
+	case r == eof || r == '\n':
+		return l.errorf(l.open, "unclosed expression")
+	case r == delim:
+		l.emit(itemRightDelim)
+		return lexText
+	case r == ' ' || r == '\t':
+		l.ignore()
+	case r == '_' && l.peek() == '_':
+		l.next()
+		l.emit(itemSeparator)
+	case isIdentRune(r):
+		return lexIdentifier
+	case r == '"':
+		return lexQuote
+	case r == ';':
+		l.emit(itemSemicolon)
+	case r == '=':
+		l.emit(itemAssign)
+	case r == '(':
+		l.emit(itemLeftParen)
+	case r == ')':
+		l.emit(itemRightParen)
+	case r == ',':
+		l.emit(itemComma)
+	case r == '.':
+		l.emit(itemDot)
+	case r == '!':
+		l.emit(itemNot)
+	default:
+		return l.errorf(l.start, "unexpected %q in expression", r)
+	}
+	return lexInsideExpr
+}
+
+// lexIdentifier scans a name.  A double underscore terminates the name since it
+// separates a field from its formatters in the short format.
+func lexIdentifier(l *lexer) stateFn {
+	for {
+		r := l.next()
+		if r == '_' && l.peek() == '_' {
+			l.backup()
+			break
+		}
+		if !isIdentRune(r) && r != '-' {
+			l.backup()
+			break
+		}
+	}
+	l.emit(itemIdentifier)
+	return lexInsideExpr
+}
+
+// lexQuote scans a quoted string.  The opening quote is known to be present.
+// Within parentheses quotes begin nested strings, so formatter arguments can be
+// written naturally e.g. format="replace("-","_")"
+func lexQuote(l *lexer) stateFn {
+	depth := 0
+	for {
+		switch l.next() {
+		case '\\':
+			if r := l.next(); r != eof && r != '\n' {
+				break
+			}
+			return l.errorf(l.start, "unterminated quoted string")
+		case eof, '\n':
+			return l.errorf(l.start, "unterminated quoted string")
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case '"':
+			if depth == 0 {
+				l.emit(itemString)
+				return lexInsideExpr
+			}
+			if !l.scanNestedQuote() {
+				return l.errorf(l.start, "unterminated quoted string")
+			}
+		}
+	}
+}
+
+// scanNestedQuote scans the remainder of a string nested within a quoted
+// string, reporting whether it was terminated.
+func (l *lexer) scanNestedQuote() bool {
+	for {
+		switch l.next() {
+		case '\\':
+			if r := l.next(); r == eof || r == '\n' {
+				return false
+			}
+		case eof, '\n':
+			return false
+		case '"':
+			return true
+		}
+	}
+}
+
+func isNameStart(r rune) bool {
+	return r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9'
+}
@@ -0,0 +1,156 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func types(items []item) []itemType {
+	results := []itemType{}
+	for _, it := range items {
+		results = append(results, it.typ)
+	}
+	return results
+}
+
+func TestLexShortFormat(t *testing.T) {
+	Convey("Given a short format template", t, func() {
+		items := lex([]byte(`before_$hello__world__argle$_after`))
+
+		Convey("Then I expect the field and formatters to be separated", func() {
+			So(types(items), ShouldResemble, []itemType{
+				itemText, itemLeftDelim,
+				itemIdentifier, itemSeparator, itemIdentifier, itemSeparator, itemIdentifier,
+				itemRightDelim, itemText, itemEOF,
+			})
+			So(items[0].val, ShouldEqual, "before_")
+			So(items[2].val, ShouldEqual, "hello")
+			So(items[4].val, ShouldEqual, "world")
+			So(items[6].val, ShouldEqual, "argle")
+			So(items[8].val, ShouldEqual, "_after")
+		})
+	})
+}
+
+func TestLexLongFormat(t *testing.T) {
+	Convey("Given a long format template", t, func() {
+		items := lex([]byte(`before_$name;format="normalize,lower"$_after`))
+
+		Convey("Then I expect the format option to be lexed", func() {
+			So(types(items), ShouldResemble, []itemType{
+				itemText, itemLeftDelim,
+				itemIdentifier, itemSemicolon, itemIdentifier, itemAssign, itemString,
+				itemRightDelim, itemText, itemEOF,
+			})
+			So(items[6].val, ShouldEqual, `"normalize,lower"`)
+			So(items[6].pos, ShouldEqual, 20)
+		})
+	})
+}
+
+func TestLexEscape(t *testing.T) {
+	Convey("Given text with an escaped $", t, func() {
+		items := lex([]byte(`-> \$ <-`))
+
+		Convey("Then I expect a single text item with the escape resolved", func() {
+			So(types(items), ShouldResemble, []itemType{itemText, itemEOF})
+			So(items[0].val, ShouldEqual, `-> $ <-`)
+		})
+	})
+
+	Convey("Given text with a backslash that does not precede a $", t, func() {
+		items := lex([]byte(`C:\temp`))
+
+		Convey("Then the backslash is kept", func() {
+			So(items[0].val, ShouldEqual, `C:\temp`)
+		})
+	})
+}
+
+func TestLexErrors(t *testing.T) {
+	Convey("An unterminated string in an expression is an error", t, func() {
+		items := lex([]byte("cost $name;format=\"upper$\nmore"))
+		last := items[len(items)-1]
+		So(last.typ, ShouldEqual, itemError)
+		So(last.pos, ShouldEqual, 18)
+	})
+
+	Convey("An unexpected character in an expression is an error", t, func() {
+		items := lex([]byte("$if(x?)$"))
+		last := items[len(items)-1]
+		So(last.typ, ShouldEqual, itemError)
+		So(last.pos, ShouldEqual, 5)
+	})
+}
+
+func TestLexLiteralDollar(t *testing.T) {
+	Convey("A $ that doesn't open a giter8 expression is text", t, func() {
+		for _, text := range []string{
+			`{{ range $k, $v := .Values.env }}`,
+			`{{ $.Values.image.tag }}`,
+			`{{- $x := 1 }}{{ $x }}`,
+			"echo $HOME\nexport PATH=$PATH:$GOPATH/bin\n",
+			"cd ${BUILD_DIR}",
+			"costs $5, or $10 for two",
+			"all: $(OBJS)\n\t$(CC) -o $@ $$x",
+			"$name?$",
+			"$organization",
+			`{{ $root.Values.image }}:{{ $root.Values.tag }}`,
+			`echo "$a.$b"`,
+			`VERSION="$MAJOR.$MINOR"`,
+			`s"$a;$b"`,
+			`$name;style="upper"$`,
+		} {
+			items := lex([]byte(text))
+			So(types(items), ShouldResemble, []itemType{itemText, itemEOF})
+			So(items[0].val, ShouldEqual, text)
+		}
+	})
+
+	Convey("Expressions may share a line with other uses of $", t, func() {
+		items := lex([]byte(`echo $HOME $name$`))
+		So(types(items), ShouldResemble, []itemType{itemText, itemLeftDelim, itemIdentifier, itemRightDelim, itemEOF})
+		So(items[0].val, ShouldEqual, "echo $HOME ")
+	})
+
+	Convey("Expressions are recognised by what follows the name", t, func() {
+		for _, text := range []string{
+			`$name$`,
+			`$name__upper$`,
+			`$name;format="upper"$`,
+			`$name; format = "upper"$`,
+			`$if(x.truthy)$`,
+			`$else$`,
+			`$endif$`,
+			`$for(m in modules)$`,
+			`$endfor$`,
+			`$include("partials/a.txt")$`,
+		} {
+			items := lex([]byte(text))
+			So(items[0].typ, ShouldEqual, itemLeftDelim)
+			So(items[len(items)-1].typ, ShouldEqual, itemEOF)
+		}
+	})
+}
@@ -0,0 +1,254 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"unicode"
+)
+
+// Native returns a text/template, for templates written in native Go template
+// syntax, with the formatters and the helpers in NativeFuncs available as
+// functions.  Formatters in funcs take precedence over registered formatters
+// of the same name.
+func Native(name string, funcs ...FuncMap) *texttemplate.Template {
+	return texttemplate.New(name).Funcs(NativeFuncs(funcs...))
+}
+
+// NativeFuncs returns the registered formatters, those in funcs and the helper
+// library as a text/template FuncMap.  Only formatters whose names are Go
+// identifiers can be called from text/template, so e.g. snake-case is omitted
+// in favour of snake.  As with other text/template functions the value being
+// formatted is the last argument, so formatters taking arguments can be
+// pipelined e.g. {{ .name | truncate 20 | upper }}.
+func NativeFuncs(funcs ...FuncMap) texttemplate.FuncMap {
+	native := texttemplate.FuncMap{}
+	for name, fn := range helpers {
+		native[name] = fn
+	}
+
+	formatters := FuncMap{}
+	for _, name := range registry.Formatters() {
+		formatters[name], _ = registry.Lookup(name)
+	}
+	for _, funcMap := range funcs {
+		for name, fn := range funcMap {
+			formatters[name] = fn
+		}
+	}
+	for name, fn := range formatters {
+		if isIdentifier(name) {
+			native[name] = valueLast(fn)
+		}
+	}
+	return native
+}
+
+// isIdentifier reports whether name can be called from text/template
+func isIdentifier(name string) bool {
+	for index, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (index == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// valueLast adapts a formatter taking further arguments so that the value is
+// its last argument rather than its first
+func valueLast(fn interface{}) interface{} {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.NumIn() == 1 {
+		return fn
+	}
+
+	in := []reflect.Type{}
+	for i := 1; i < t.NumIn(); i++ {
+		in = append(in, t.In(i))
+	}
+	in = append(in, t.In(0))
+	out := []reflect.Type{}
+	for i := 0; i < t.NumOut(); i++ {
+		out = append(out, t.Out(i))
+	}
+
+	adapted := reflect.FuncOf(in, out, false)
+	return reflect.MakeFunc(adapted, func(args []reflect.Value) []reflect.Value {
+		last := len(args) - 1
+		return v.Call(append([]reflect.Value{args[last]}, args[:last]...))
+	}).Interface()
+}
+
+// helpers are the string, list and map functions available to native
+// templates in addition to the formatters.  Like the formatters, they take the
+// value they operate on last.
+var helpers = texttemplate.FuncMap{
+	// strings
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+	"indent":     indent,
+	"quote":      func(s string) string { return fmt.Sprintf("%q", s) },
+	"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+
+	// lists
+	"items": splitItems,
+	"list":  func(values ...interface{}) []interface{} { return values },
+	"join":  join,
+	"first": func(values interface{}) (interface{}, error) { return at(values, 0) },
+	"last":  func(values interface{}) (interface{}, error) { return at(values, -1) },
+	"has":   has,
+	"uniq":  uniq,
+
+	// maps
+	"dict":   dict,
+	"keys":   mapKeys,
+	"hasKey": hasKey,
+}
+
+// indent prefixes every non-empty line of s with n spaces
+func indent(n int, s string) string {
+	prefix := strings.Repeat(" ", n)
+	lines := strings.Split(s, "\n")
+	for index, line := range lines {
+		if line != "" {
+			lines[index] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// elements returns the elements of a slice or array
+func elements(values interface{}) ([]interface{}, error) {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected a list, got %T", values)
+	}
+	result := make([]interface{}, v.Len())
+	for i := range result {
+		result[i] = v.Index(i).Interface()
+	}
+	return result, nil
+}
+
+// join joins the elements of a list with sep
+func join(sep string, values interface{}) (string, error) {
+	elems, err := elements(values)
+	if err != nil {
+		return "", err
+	}
+	texts := make([]string, len(elems))
+	for index, elem := range elems {
+		texts[index] = fmt.Sprint(elem)
+	}
+	return strings.Join(texts, sep), nil
+}
+
+// at returns the element at index, counting from the end if negative
+func at(values interface{}, index int) (interface{}, error) {
+	elems, err := elements(values)
+	if err != nil || len(elems) == 0 {
+		return nil, err
+	}
+	if index < 0 {
+		index += len(elems)
+	}
+	return elems[index], nil
+}
+
+// has reports whether the list holds needle
+func has(needle interface{}, values interface{}) (bool, error) {
+	elems, err := elements(values)
+	if err != nil {
+		return false, err
+	}
+	for _, elem := range elems {
+		if reflect.DeepEqual(elem, needle) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// uniq removes repeated elements from a list, keeping the first of each
+func uniq(values interface{}) ([]interface{}, error) {
+	elems, err := elements(values)
+	if err != nil {
+		return nil, err
+	}
+	result := []interface{}{}
+	for _, elem := range elems {
+		if ok, _ := has(elem, result); !ok {
+			result = append(result, elem)
+		}
+	}
+	return result, nil
+}
+
+// dict builds a map from alternating keys and values
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict expects an even number of arguments, got %d", len(pairs))
+	}
+	m := map[string]interface{}{}
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, got %T", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// hasKey reports whether a map with string keys, such as the fields, holds key
+func hasKey(key string, m interface{}) (bool, error) {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return false, fmt.Errorf("expected a map, got %T", m)
+	}
+	return v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())).IsValid(), nil
+}
+
+// mapKeys returns the sorted keys of a map with string keys
+func mapKeys(m interface{}) ([]string, error) {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("expected a map, got %T", m)
+	}
+	names := []string{}
+	for _, key := range v.MapKeys() {
+		names = append(names, key.String())
+	}
+	sort.Strings(names)
+	return names, nil
+}
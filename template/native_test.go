@@ -0,0 +1,96 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestNative(t *testing.T) {
+	fields := map[string]string{"name": "Order Service", "modules": "api, worker,api", "docker": "yes"}
+
+	render := func(text string, funcs ...FuncMap) (string, error) {
+		tmpl, err := Native("test", funcs...).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", err
+		}
+		buffer := bytes.NewBuffer([]byte{})
+		err = tmpl.Execute(buffer, fields)
+		return buffer.String(), err
+	}
+
+	Convey("Formatters can be used as functions", t, func() {
+		for text, expected := range map[string]string{
+			`{{ .name | norm }}`:                            "order-service",
+			`{{ snake .name | upper }}`:                     "ORDER_SERVICE",
+			`{{ .name | truncate 5 | lower }}`:              "order",
+			`{{ .name | replace " " "" }}`:                  "OrderService",
+			`{{ index . "missing" | default "none" }}`:      "none",
+			`{{ if eq (.docker | upper) "YES" }}y{{ end }}`: "y",
+		} {
+			value, err := render(text)
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, expected)
+		}
+	})
+
+	Convey("Helpers work with lists and maps", t, func() {
+		for text, expected := range map[string]string{
+			`{{ range items .modules }}[{{ . }}]{{ end }}`:                  "[api][worker][api]",
+			`{{ items .modules | uniq | join "," }}`:                        "api,worker",
+			`{{ items .modules | first }}-{{ items .modules | last }}`:      "api-api",
+			`{{ if items .modules | has "worker" }}yes{{ end }}`:            "yes",
+			`{{ $d := dict "a" 1 "b" 2 }}{{ keys $d | join "," }}`:          "a,b",
+			`{{ if hasKey "docker" . }}docker{{ end }}`:                     "docker",
+			`{{ "a\nb" | indent 2 }}`:                                       "  a\n  b",
+			`{{ .name | trimPrefix "Order " | quote }}`:                     `"Service"`,
+			`{{ with $parts := split " " .name }}{{ len $parts }}{{ end }}`: "2",
+		} {
+			value, err := render(text)
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, expected)
+		}
+	})
+
+	Convey("Formatters with names that aren't identifiers are omitted", t, func() {
+		funcs := NativeFuncs()
+		_, ok := funcs["snake-case"]
+		So(ok, ShouldBeFalse)
+		_, ok = funcs["snake"]
+		So(ok, ShouldBeTrue)
+	})
+
+	Convey("Formatters given to the template take precedence", t, func() {
+		value, err := render(`{{ .name | upper }}`, FuncMap{"upper": func(s string) string { return "shout" }})
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "shout")
+	})
+
+	Convey("Formatter errors stop execution", t, func() {
+		_, err := render(`{{ .name | truncate -1 }}`)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "length must not be negative")
+	})
+}
@@ -0,0 +1,171 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Pos is the byte offset of a node in the original template text.
+type Pos int
+
+func (p Pos) Position() Pos {
+	return p
+}
+
+// Node is an element in the parse tree of a giter8 template.
+type Node interface {
+	Position() Pos
+	// String returns the giter8 source for the node.
+	String() string
+}
+
+// ListNode holds a sequence of nodes.
+type ListNode struct {
+	Pos
+	Nodes []Node
+}
+
+func (l *ListNode) append(n Node) {
+	l.Nodes = append(l.Nodes, n)
+}
+
+func (l *ListNode) String() string {
+	buffer := bytes.NewBuffer([]byte{})
+	for _, n := range l.Nodes {
+		buffer.WriteString(n.String())
+	}
+	return buffer.String()
+}
+
+// TextNode holds literal text that is emitted verbatim.
+type TextNode struct {
+	Pos
+	Text []byte
+}
+
+func (t *TextNode) String() string {
+	return strings.Replace(string(t.Text), "$", `\$`, -1)
+}
+
+// FieldNode holds a field reference along with the formatters to apply to it
+// e.g. $name__upper$ or $name;format="upper"$
+type FieldNode struct {
+	Pos
+	Name       string
+	Formatters []string
+	calls      []formatterCall // formatters resolved when the template was parsed
+	text       string          // original source, including delimiters
+}
+
+func (f *FieldNode) String() string {
+	return f.text
+}
+
+// defaulted reports whether the field supplies its own default value, in which
+// case it is never considered undefined
+func (f *FieldNode) defaulted() bool {
+	for _, name := range f.Formatters {
+		if name == "default" {
+			return true
+		}
+	}
+	return false
+}
+
+// IfNode holds a conditional block e.g.
+// $if(docker.truthy)$ ... $elseif(podman.truthy)$ ... $else$ ... $endif$
+type IfNode struct {
+	Pos
+	Branches []*Branch
+	Else     *ListNode // nil if there is no $else$
+}
+
+// Branch is a single $if$ or $elseif$ arm of a conditional block.
+type Branch struct {
+	Cond *Condition
+	List *ListNode
+}
+
+func (i *IfNode) String() string {
+	buffer := bytes.NewBuffer([]byte{})
+	for index, branch := range i.Branches {
+		keyword := "elseif"
+		if index == 0 {
+			keyword = "if"
+		}
+		fmt.Fprintf(buffer, "$%s(%s)$%s", keyword, branch.Cond, branch.List)
+	}
+	if i.Else != nil {
+		fmt.Fprintf(buffer, "$else$%s", i.Else)
+	}
+	buffer.WriteString("$endif$")
+	return buffer.String()
+}
+
+// Condition is the test of a conditional branch.  With Truthy set the field
+// must hold one of the giter8 truthy values (y, yes or true); otherwise it need
+// only be defined and non-empty.
+type Condition struct {
+	Pos
+	Name   string
+	Truthy bool
+	Not    bool
+}
+
+func (c *Condition) String() string {
+	text := c.Name
+	if c.Truthy {
+		text += ".truthy"
+	}
+	if c.Not {
+		text = "!" + text
+	}
+	return text
+}
+
+// ForNode holds a loop over the comma separated items of a field e.g.
+// $for(m in modules)$ ... $endfor$
+type ForNode struct {
+	Pos
+	Var  string // name the current item is bound to within the body
+	List string // name of the field holding the items
+	Body *ListNode
+}
+
+func (f *ForNode) String() string {
+	return fmt.Sprintf("$for(%s in %s)$%s$endfor$", f.Var, f.List, f.Body)
+}
+
+// IncludeNode holds a reference to a partial template rendered in its place
+// e.g. $include("partials/header.txt")$
+type IncludeNode struct {
+	Pos
+	Name string // path of the partial, relative to the template root
+}
+
+func (i *IncludeNode) String() string {
+	return fmt.Sprintf("$include(%q)$", i.Name)
+}
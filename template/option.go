@@ -0,0 +1,91 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"fmt"
+	"strings"
+)
+
+// missingKeyAction defines how to respond to a template referencing a field
+// that is not present in the data.
+type missingKeyAction int
+
+const (
+	mapZeroValue missingKeyAction = iota // render an empty string
+	mapError                             // stop execution, reporting every undefined field
+	mapKeep                              // leave the original $...$ expression in the output
+)
+
+type option struct {
+	missingKey missingKeyAction
+}
+
+// Option sets options for the template.  Options are described by strings,
+// either a simple string or "key=value".  There can be at most one equals sign
+// in an option string.  If the option string is unrecognized or otherwise
+// invalid, Option panics.
+//
+// Known options:
+//
+// missingkey: Control the behavior during execution if a template references a
+// field that is not present in the data.
+//
+//	"missingkey=default" or "missingkey=zero"
+//		The default behavior: render an empty string.
+//	"missingkey=error"
+//		Execution fails with an *UndefinedError listing every undefined
+//		field referenced by the template.
+//	"missingkey=keep"
+//		The original expression, e.g. $name__upper$, is left untouched.
+func (t *Template) Option(opt ...string) *Template {
+	for _, s := range opt {
+		t.setOption(s)
+	}
+	return t
+}
+
+func (t *Template) setOption(opt string) {
+	if opt == "" {
+		panic("empty option string")
+	}
+	elems := strings.Split(opt, "=")
+	switch len(elems) {
+	case 2:
+		switch elems[0] {
+		case "missingkey":
+			switch elems[1] {
+			case "zero", "default":
+				t.option.missingKey = mapZeroValue
+				return
+			case "error":
+				t.option.missingKey = mapError
+				return
+			case "keep":
+				t.option.missingKey = mapKeep
+				return
+			}
+		}
+	}
+	panic(fmt.Sprintf("unrecognized option: %s", opt))
+}
@@ -0,0 +1,607 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parser builds the parse tree for a single template from its lex items.
+type parser struct {
+	name     string
+	funcs    FuncMap // formatters specific to this template
+	input    []byte
+	items    []item
+	pos      int  // index of the next item
+	open     int  // position of the $ opening the expression being parsed, or -1
+	trimNext bool // strip the line ending from the next text item
+	midLine  bool // the input starts part way through a line
+}
+
+// control is a block keyword such as $if(...)$ or $endif$ found while parsing
+// a list.
+type control struct {
+	keyword string
+	pos     int
+	cond    *Condition // set for if and elseif
+	loop    *ForNode   // set for for; the body is parsed separately
+}
+
+// parse converts giter8 text into a parse tree.  Formatters are resolved
+// against funcs and then the registry so typos are reported before anything is
+// executed.
+func parse(name string, text []byte, funcs FuncMap) (root *ListNode, err error) {
+	return parseSegment(name, text, funcs, false)
+}
+
+// parseSegment parses a segment of a larger template.  midLine is set when the
+// segment starts part way through a line, so the keywords on its first line
+// are never on a line of their own.
+func parseSegment(name string, text []byte, funcs FuncMap, midLine bool) (root *ListNode, err error) {
+	p := &parser{
+		name:    name,
+		funcs:   funcs,
+		input:   text,
+		items:   lex(text),
+		open:    -1,
+		midLine: midLine,
+	}
+	defer p.recover(&err)
+
+	root, end := p.list()
+	if end != nil {
+		p.unmatched(end)
+	}
+	return root, nil
+}
+
+// list parses nodes until the end of the input or a control keyword that
+// belongs to an enclosing block, which is returned to the caller.
+func (p *parser) list() (*ListNode, *control) {
+	list := &ListNode{Pos: Pos(p.peek().pos)}
+	for {
+		switch it := p.next(); it.typ {
+		case itemEOF:
+			return list, nil
+		case itemText:
+			text := it.val
+			if p.trimNext {
+				text = trimLeadingLine(text)
+				p.trimNext = false
+			}
+			if text != "" {
+				list.append(&TextNode{Pos: Pos(it.pos), Text: []byte(text)})
+			}
+		case itemLeftDelim:
+			p.open = it.pos
+			if c := p.control(it, list); c != nil {
+				p.open = -1
+				switch c.keyword {
+				case "if":
+					list.append(p.conditional(c))
+				case "for":
+					list.append(p.forLoop(c))
+				default:
+					return list, c
+				}
+				continue
+			}
+			if n := p.include(it, list); n != nil {
+				p.open = -1
+				list.append(n)
+				continue
+			}
+			list.append(p.expression(it))
+			p.open = -1
+		default:
+			p.unexpected(it)
+		}
+	}
+}
+
+// conditional parses the branches of an $if$ block up to the matching $endif$.
+func (p *parser) conditional(start *control) Node {
+	node := &IfNode{Pos: Pos(start.pos)}
+	cond := start.cond
+	for {
+		body, end := p.list()
+		if end == nil {
+			p.errorf(start.pos, "unclosed $if$; missing $endif$")
+		}
+		node.Branches = append(node.Branches, &Branch{Cond: cond, List: body})
+
+		switch end.keyword {
+		case "elseif":
+			cond = end.cond
+		case "else":
+			body, end = p.list()
+			if end == nil {
+				p.errorf(start.pos, "unclosed $if$; missing $endif$")
+			}
+			if end.keyword != "endif" {
+				p.errorf(end.pos, "only $endif$ may follow $else$")
+			}
+			node.Else = body
+			return node
+		case "endif":
+			return node
+		default:
+			p.unmatched(end)
+		}
+	}
+}
+
+// forLoop parses the body of a $for$ loop up to the matching $endfor$.
+func (p *parser) forLoop(start *control) Node {
+	body, end := p.list()
+	if end == nil {
+		p.errorf(start.pos, "unclosed $for$; missing $endfor$")
+	}
+	if end.keyword != "endfor" {
+		p.unmatched(end)
+	}
+	start.loop.Body = body
+	return start.loop
+}
+
+// unmatched reports a closing keyword that doesn't belong to an enclosing block
+func (p *parser) unmatched(end *control) {
+	if end.keyword == "endfor" {
+		p.errorf(end.pos, "no matching $for$")
+	}
+	p.errorf(end.pos, "no matching $if$")
+}
+
+// control parses a block keyword if the expression opened by open is one.
+// Otherwise nothing is consumed and nil is returned.  A keyword that sits on a
+// line of its own swallows that line so blocks don't leave blank lines behind.
+func (p *parser) control(open item, list *ListNode) *control {
+	name := p.peek()
+	if name.typ != itemIdentifier {
+		return nil
+	}
+	following := p.items[p.pos+1].typ
+
+	c := &control{keyword: name.val, pos: open.pos}
+	switch {
+	case (name.val == "if" || name.val == "elseif") && following == itemLeftParen:
+		p.next()
+		p.next()
+		c.cond = p.condition()
+		p.expect(itemRightParen, "condition")
+	case name.val == "for" && following == itemLeftParen:
+		p.next()
+		p.next()
+		c.loop = p.loopHeader(open.pos)
+		p.expect(itemRightParen, "loop")
+	case (name.val == "else" || name.val == "endif" || name.val == "endfor") && following == itemRightDelim:
+		p.next()
+	default:
+		return nil
+	}
+	end := p.expect(itemRightDelim, "$"+name.val+"$")
+	p.trimStandalone(open.pos, end.pos+1, list)
+	return c
+}
+
+// include parses $include("path")$ if the expression opened by open is one.
+// Otherwise nothing is consumed and nil is returned.
+func (p *parser) include(open item, list *ListNode) Node {
+	name := p.peek()
+	if name.typ != itemIdentifier || name.val != "include" || p.items[p.pos+1].typ != itemLeftParen {
+		return nil
+	}
+	p.next()
+	p.next()
+
+	quoted := p.expect(itemString, "include")
+	path, err := strconv.Unquote(quoted.val)
+	if err != nil || path == "" {
+		p.errorf(quoted.pos, "invalid path %s in include", quoted.val)
+	}
+	p.expect(itemRightParen, "include")
+	end := p.expect(itemRightDelim, "include")
+
+	// a partial normally ends with its own line ending
+	p.trimStandalone(open.pos, end.pos+1, list)
+	return &IncludeNode{Pos: Pos(open.pos), Name: path}
+}
+
+// trimStandalone swallows the line holding input[start:end] if there is
+// nothing else on it, so that keywords don't leave blank lines behind.
+func (p *parser) trimStandalone(start, end int, list *ListNode) {
+	if !p.standalone(start, end) {
+		return
+	}
+	if n := len(list.Nodes); n > 0 {
+		if text, ok := list.Nodes[n-1].(*TextNode); ok {
+			text.Text = bytes.TrimRight(text.Text, " \t")
+			if len(text.Text) == 0 {
+				list.Nodes = list.Nodes[:n-1]
+			}
+		}
+	}
+	p.trimNext = true
+}
+
+// condition parses the test inside $if(...)$ e.g. !docker.truthy
+func (p *parser) condition() *Condition {
+	cond := &Condition{Pos: Pos(p.peek().pos)}
+	if p.peek().typ == itemNot {
+		p.next()
+		cond.Not = true
+	}
+	cond.Name = p.expect(itemIdentifier, "condition").val
+	if p.peek().typ == itemDot {
+		p.next()
+		attr := p.expect(itemIdentifier, "condition")
+		if attr.val != "truthy" {
+			p.errorf(attr.pos, "unknown attribute %q; expected truthy", attr.val)
+		}
+		cond.Truthy = true
+	}
+	return cond
+}
+
+// loopHeader parses the variable and list inside $for(...)$ e.g. m in modules
+func (p *parser) loopHeader(pos int) *ForNode {
+	loop := &ForNode{Pos: Pos(pos)}
+	loop.Var = p.expect(itemIdentifier, "loop").val
+	if in := p.expect(itemIdentifier, "loop"); in.val != "in" {
+		p.errorf(in.pos, "unexpected %s in loop; expected in", in)
+	}
+	loop.List = p.expect(itemIdentifier, "loop").val
+	return loop
+}
+
+// standalone reports whether the only other characters on the line(s) holding
+// input[start:end] are spaces or tabs.
+func (p *parser) standalone(start, end int) bool {
+	i := start - 1
+	for ; i >= 0 && p.input[i] != '\n'; i-- {
+		if p.input[i] != ' ' && p.input[i] != '\t' {
+			return false
+		}
+	}
+	if i < 0 && p.midLine {
+		return false
+	}
+	for i := end; i < len(p.input) && p.input[i] != '\n'; i++ {
+		if p.input[i] != ' ' && p.input[i] != '\t' && p.input[i] != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// trimLeadingLine removes the remainder of the first line, up to and including
+// its line ending
+func trimLeadingLine(text string) string {
+	index := strings.IndexByte(text, '\n')
+	if index < 0 || strings.TrimSpace(text[:index]) != "" {
+		return text
+	}
+	return text[index+1:]
+}
+
+func (p *parser) next() item {
+	it := p.items[p.pos]
+	if it.typ == itemError {
+		p.errorf(it.pos, "%s", it.val)
+	}
+	if it.typ != itemEOF {
+		p.pos++
+	}
+	return it
+}
+
+func (p *parser) peek() item {
+	return p.items[p.pos]
+}
+
+// expect consumes the next item and guarantees it has the required type.
+func (p *parser) expect(typ itemType, context string) item {
+	it := p.next()
+	if it.typ != typ {
+		p.errorf(it.pos, "unexpected %s in %s", it, context)
+	}
+	return it
+}
+
+func (p *parser) unexpected(it item) {
+	p.errorf(it.pos, "unexpected %s", it)
+}
+
+// errorf aborts the parse; the error is picked up by recover.
+func (p *parser) errorf(pos int, format string, args ...interface{}) {
+	p.fail(pos, "", format, args...)
+}
+
+// fail aborts the parse with an error that includes a suggested correction
+func (p *parser) fail(pos int, suggestion string, format string, args ...interface{}) {
+	line, col := position(p.input, pos)
+	open := p.open
+	if open < 0 {
+		open = pos
+	}
+	panic(parseError{&Error{
+		Name:       p.name,
+		Line:       line,
+		Col:        col,
+		Expr:       exprAt(p.input, open),
+		Message:    fmt.Sprintf(format, args...),
+		Suggestion: suggestion,
+	}})
+}
+
+// parseError wraps errors raised while parsing so recover can tell them apart
+// from genuine runtime panics.
+type parseError struct {
+	err error
+}
+
+func (p *parser) recover(errp *error) {
+	if e := recover(); e != nil {
+		pe, ok := e.(parseError)
+		if !ok {
+			panic(e)
+		}
+		*errp = pe.err
+	}
+}
+
+// expression parses the contents of a $...$ expression.  The opening
+// delimiter has already been consumed.
+func (p *parser) expression(open item) Node {
+	name := p.expect(itemIdentifier, "expression")
+	field := &FieldNode{Pos: Pos(open.pos), Name: name.val}
+
+	switch it := p.next(); it.typ {
+	case itemSeparator:
+		// short format: $name__filter1__filter2$
+		for {
+			f := p.expect(itemIdentifier, "formatter list")
+			p.formatter(field, f.pos, f.val, nil)
+			if p.peek().typ != itemSeparator {
+				break
+			}
+			p.next()
+		}
+		p.expect(itemRightDelim, "expression")
+
+	case itemSemicolon:
+		// long format: $name;format="filter1,filter2"$
+		option := p.expect(itemIdentifier, "expression")
+		if option.val != "format" {
+			p.errorf(option.pos, "unknown option %q", option.val)
+		}
+		p.expect(itemAssign, "expression")
+		quoted := p.expect(itemString, "expression")
+		p.formatList(field, quoted)
+		p.expect(itemRightDelim, "expression")
+
+	case itemRightDelim:
+		// plain field: $name$
+
+	default:
+		p.unexpected(it)
+	}
+
+	field.text = string(p.input[open.pos : p.items[p.pos-1].pos+1])
+	return field
+}
+
+// formatList parses the comma separated formatters, with optional arguments,
+// in the quoted value of format="..." e.g. "lower,truncate(20),replace("-","_")".
+// A formatter taking a single string may instead be written name:argument.
+func (p *parser) formatList(field *FieldNode, quoted item) {
+	value := unescapeQuotes(quoted.val[1 : len(quoted.val)-1])
+	base := quoted.pos + 1 // positions assume the value contains no escapes
+
+	i := 0
+	skipSpace := func() {
+		for i < len(value) && (value[i] == ' ' || value[i] == '\t') {
+			i++
+		}
+	}
+
+	for {
+		skipSpace()
+		start := i
+		for i < len(value) && (isIdentRune(rune(value[i])) || value[i] == '-') {
+			i++
+		}
+		if i == start {
+			p.errorf(base+i, "missing formatter name")
+		}
+		name := value[start:i]
+
+		args := []arg{}
+		if i < len(value) && value[i] == ':' {
+			// a single argument up to the next comma that's followed by a
+			// formatter e.g. date:2006-01-02 or truncate:20, converted to the
+			// type of the formatter's parameter.  Other commas are part of the
+			// argument, as in date:Jan 2, 2006.
+			end := i + 1
+			for {
+				comma := strings.IndexByte(value[end:], ',')
+				if comma < 0 {
+					end = len(value)
+					break
+				}
+				end += comma
+				if p.isFormatter(value[end+1:]) {
+					break
+				}
+				end++
+			}
+			args = append(args, arg{pos: base + i + 1, value: strings.TrimSpace(value[i+1 : end]), bare: true})
+			i = end
+		}
+		skipSpace()
+		if i < len(value) && value[i] == '(' && len(args) == 0 {
+			i++
+			for {
+				skipSpace()
+				if i < len(value) && value[i] == ')' && len(args) == 0 {
+					break
+				}
+				a, n := p.argument(value[i:], base+i)
+				args = append(args, a)
+				i += n
+				skipSpace()
+				if i < len(value) && value[i] == ',' {
+					i++
+					continue
+				}
+				break
+			}
+			if i >= len(value) || value[i] != ')' {
+				p.errorf(base+i, "missing ) after arguments to %q", name)
+			}
+			i++
+			skipSpace()
+		}
+		p.formatter(field, base+start, name, args)
+
+		if i >= len(value) {
+			return
+		}
+		if value[i] != ',' {
+			p.errorf(base+i, "unexpected %q in format", value[i])
+		}
+		i++
+	}
+}
+
+// isFormatter reports whether text, the rest of a format after a comma, starts
+// with the name of a formatter
+func (p *parser) isFormatter(text string) bool {
+	text = strings.TrimLeft(text, " \t")
+	end := 0
+	for end < len(text) && (isIdentRune(rune(text[end])) || text[end] == '-') {
+		end++
+	}
+	if end == 0 {
+		return false
+	}
+	if _, ok := p.funcs[text[:end]]; ok {
+		return true
+	}
+	_, ok := registry.Lookup(text[:end])
+	return ok
+}
+
+// arg is a literal argument to a formatter; either a string or an int
+type arg struct {
+	pos   int
+	value interface{}
+	bare  bool // given after a colon, so a string to convert to the parameter's type
+}
+
+// argument parses the literal argument at the start of text, returning it and
+// the number of bytes consumed
+func (p *parser) argument(text string, pos int) (arg, int) {
+	if strings.HasPrefix(text, `"`) {
+		for i := 1; i < len(text); i++ {
+			switch text[i] {
+			case '\\':
+				i++
+			case '"':
+				value, err := strconv.Unquote(text[:i+1])
+				if err != nil {
+					p.errorf(pos, "invalid string argument %s", text[:i+1])
+				}
+				return arg{pos: pos, value: value}, i + 1
+			}
+		}
+		p.errorf(pos, "unterminated string argument")
+	}
+
+	i := 0
+	if i < len(text) && (text[i] == '-' || text[i] == '+') {
+		i++
+	}
+	for i < len(text) && '0' <= text[i] && text[i] <= '9' {
+		i++
+	}
+	value, err := strconv.Atoi(text[:i])
+	if err != nil {
+		end := strings.IndexAny(text, ",)")
+		if end < 0 {
+			end = len(text)
+		}
+		p.errorf(pos, "invalid argument %q; expected a number or a quoted string", strings.TrimSpace(text[:end]))
+	}
+	return arg{pos: pos, value: value}, i
+}
+
+// unescapeQuotes allows the quotes of string arguments to be escaped, as in
+// format="replace(\"-\",\"_\")", provided none of them are unescaped
+func unescapeQuotes(value string) string {
+	if !strings.Contains(value, `\"`) || strings.Contains(strings.Replace(value, `\"`, "", -1), `"`) {
+		return value
+	}
+	return strings.Replace(strings.Replace(value, `\"`, `"`, -1), `\\`, `\`, -1)
+}
+
+// formatter resolves the named formatter, checks the arguments against its
+// signature and appends it to the field
+func (p *parser) formatter(field *FieldNode, pos int, name string, args []arg) {
+	fn, ok := p.funcs[name]
+	if !ok {
+		fn, ok = registry.Lookup(name)
+	}
+	if !ok {
+		names := registry.Formatters()
+		for candidate := range p.funcs {
+			names = append(names, candidate)
+		}
+		p.fail(pos, suggest(name, names), "unknown formatter %q", name)
+	}
+
+	values, err := checkArgs(fn, args)
+	if err != nil {
+		p.errorf(pos, "formatter %q: %s", name, err)
+	}
+
+	field.Formatters = append(field.Formatters, name)
+	field.calls = append(field.calls, formatterCall{fn: fn, args: values})
+}
+
+// position converts a byte offset into a 1-based line and column
+func position(text []byte, pos int) (line, col int) {
+	line, col = 1, 1
+	for _, r := range string(text[:pos]) {
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
@@ -0,0 +1,200 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestParseTree(t *testing.T) {
+	Convey("Given a template with text and both expression formats", t, func() {
+		root, err := parse("test", []byte(`a $x__upper__lower$ b $y;format="snake, cap"$`), nil)
+
+		Convey("Then I expect text and field nodes", func() {
+			So(err, ShouldBeNil)
+			So(len(root.Nodes), ShouldEqual, 4)
+
+			short := root.Nodes[1].(*FieldNode)
+			So(short.Name, ShouldEqual, "x")
+			So(short.Formatters, ShouldResemble, []string{"upper", "lower"})
+			So(short.Position(), ShouldEqual, Pos(2))
+
+			long := root.Nodes[3].(*FieldNode)
+			So(long.Name, ShouldEqual, "y")
+			So(long.Formatters, ShouldResemble, []string{"snake", "cap"})
+		})
+
+		Convey("Then the tree can be printed back as giter8 source", func() {
+			So(root.String(), ShouldEqual, `a $x__upper__lower$ b $y;format="snake, cap"$`)
+		})
+	})
+
+	Convey("Given a template with an option other than format", t, func() {
+		root, err := parse("test", []byte(`$name;style="upper"$`), nil)
+
+		Convey("Then I expect it to be text", func() {
+			So(err, ShouldBeNil)
+			So(string(root.Nodes[0].(*TextNode).Text), ShouldEqual, `$name;style="upper"$`)
+		})
+	})
+
+	Convey("Given a template with an empty expression", t, func() {
+		root, err := parse("test", []byte(`$$`), nil)
+
+		Convey("Then I expect it to be text", func() {
+			So(err, ShouldBeNil)
+			So(string(root.Nodes[0].(*TextNode).Text), ShouldEqual, `$$`)
+		})
+	})
+}
+
+func TestPosition(t *testing.T) {
+	Convey("#position converts byte offsets to lines and columns", t, func() {
+		text := []byte("ab\ncdé\nf")
+		line, col := position(text, 0)
+		So([]int{line, col}, ShouldResemble, []int{1, 1})
+
+		line, col = position(text, 4)
+		So([]int{line, col}, ShouldResemble, []int{2, 2})
+
+		line, col = position(text, 8)
+		So([]int{line, col}, ShouldResemble, []int{3, 1})
+	})
+}
+
+func TestParseConditional(t *testing.T) {
+	Convey("Given a template with a nested conditional", t, func() {
+		text := `$if(a.truthy)$A$if(!b)$B$endif$$elseif(c.truthy)$C$else$D$endif$`
+		root, err := parse("test", []byte(text), nil)
+
+		Convey("Then I expect a single if node", func() {
+			So(err, ShouldBeNil)
+			So(len(root.Nodes), ShouldEqual, 1)
+
+			node := root.Nodes[0].(*IfNode)
+			So(len(node.Branches), ShouldEqual, 2)
+			So(node.Branches[0].Cond.Name, ShouldEqual, "a")
+			So(node.Branches[0].Cond.Truthy, ShouldBeTrue)
+			So(node.Branches[1].Cond.Name, ShouldEqual, "c")
+			So(node.Else, ShouldNotBeNil)
+
+			inner := node.Branches[0].List.Nodes[1].(*IfNode)
+			So(inner.Branches[0].Cond.Not, ShouldBeTrue)
+			So(inner.Branches[0].Cond.Truthy, ShouldBeFalse)
+		})
+
+		Convey("Then the tree can be printed back as giter8 source", func() {
+			So(root.String(), ShouldEqual, text)
+		})
+	})
+
+	Convey("A conditional on lines of its own does not leave blank lines behind", t, func() {
+		root, err := parse("test", []byte("a\n  $if(x.truthy)$\nb\n  $endif$\nc\n"), nil)
+		So(err, ShouldBeNil)
+		So(len(root.Nodes), ShouldEqual, 3)
+		So(string(root.Nodes[0].(*TextNode).Text), ShouldEqual, "a\n")
+		So(string(root.Nodes[1].(*IfNode).Branches[0].List.Nodes[0].(*TextNode).Text), ShouldEqual, "b\n")
+		So(string(root.Nodes[2].(*TextNode).Text), ShouldEqual, "c\n")
+	})
+
+	Convey("Malformed conditionals are errors", t, func() {
+		for text, message := range map[string]string{
+			`$if(x.truthy)$ no end`:            "template: test:1:1: unclosed $if$; missing $endif$ in $if(x.truthy)$",
+			`$endif$`:                          "template: test:1:1: no matching $if$ in $endif$",
+			`$if(x)$a$else$b$else$c$endif$`:    "template: test:1:16: only $endif$ may follow $else$ in $else$",
+			`$if(x.falsy)$a$endif$`:            `template: test:1:7: unknown attribute "falsy"; expected truthy in $if(x.falsy)$`,
+			`$if(x.truthy)$a$elseif(y)$$else$`: "template: test:1:1: unclosed $if$; missing $endif$ in $if(x.truthy)$",
+		} {
+			_, err := parse("test", []byte(text), nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, message)
+		}
+	})
+}
+
+func TestParseLoop(t *testing.T) {
+	Convey("Given a template with a loop", t, func() {
+		text := `$for(m in modules)$- $m$$if(m)$!$endif$$endfor$`
+		root, err := parse("test", []byte(text), nil)
+
+		Convey("Then I expect a single for node", func() {
+			So(err, ShouldBeNil)
+			So(len(root.Nodes), ShouldEqual, 1)
+
+			node := root.Nodes[0].(*ForNode)
+			So(node.Var, ShouldEqual, "m")
+			So(node.List, ShouldEqual, "modules")
+			So(len(node.Body.Nodes), ShouldEqual, 3)
+		})
+
+		Convey("Then the tree can be printed back as giter8 source", func() {
+			So(root.String(), ShouldEqual, text)
+		})
+	})
+
+	Convey("Malformed loops are errors", t, func() {
+		for text, message := range map[string]string{
+			`$for(m in modules)$ no end`:         "template: test:1:1: unclosed $for$; missing $endfor$ in $for(m in modules)$",
+			`$endfor$`:                           "template: test:1:1: no matching $for$ in $endfor$",
+			`$for(m of modules)$$endfor$`:        `template: test:1:8: unexpected "of" in loop; expected in in $for(m of modules)$`,
+			`$for(m in modules)$$endif$`:         "template: test:1:20: no matching $if$ in $endif$",
+			`$if(x)$$for(m in modules)$$endif$`:  "template: test:1:27: no matching $if$ in $endif$",
+			`$for(m in modules)$$if(x)$$endfor$`: "template: test:1:27: no matching $for$ in $endfor$",
+		} {
+			_, err := parse("test", []byte(text), nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, message)
+		}
+	})
+}
+
+func TestParseInclude(t *testing.T) {
+	Convey("An include is parsed into an include node", t, func() {
+		text := `a$include("partials/header.txt")$b`
+		root, err := parse("test", []byte(text), nil)
+		So(err, ShouldBeNil)
+		So(len(root.Nodes), ShouldEqual, 3)
+		So(root.Nodes[1].(*IncludeNode).Name, ShouldEqual, "partials/header.txt")
+		So(root.String(), ShouldEqual, text)
+	})
+
+	Convey("An include on a line of its own swallows the line ending", t, func() {
+		root, err := parse("test", []byte("a\n$include(\"h.txt\")$\nb\n"), nil)
+		So(err, ShouldBeNil)
+		So(len(root.Nodes), ShouldEqual, 3)
+		So(string(root.Nodes[2].(*TextNode).Text), ShouldEqual, "b\n")
+	})
+
+	Convey("Malformed includes are errors", t, func() {
+		for text, message := range map[string]string{
+			`$include(header)$`:    `template: test:1:10: unexpected "header" in include in $include(header)$`,
+			`$include("")$`:        `template: test:1:10: invalid path "" in include in $include("")$`,
+			`$include("a.txt" x)$`: `template: test:1:18: unexpected "x" in include in $include("a.txt" x)$`,
+		} {
+			_, err := parse("test", []byte(text), nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, message)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"code.google.com/p/go-uuid/uuid"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/big"
+	math_rand "math/rand"
+	"sync"
+)
+
+// generator produces random values from a source of random bytes.  It is safe
+// for concurrent use.
+type generator struct {
+	mu sync.Mutex
+	r  io.Reader
+}
+
+// read fills b from the random source
+func (g *generator) read(b []byte) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, err := io.ReadFull(g.r, b)
+	return err
+}
+
+// random appends a hyphen and 256 random bits in base 32 to value
+func (g *generator) random(value string) (string, error) {
+	b := make([]byte, 32)
+	if err := g.read(b); err != nil {
+		return "", err
+	}
+	return value + "-" + new(big.Int).SetBytes(b).Text(32), nil
+}
+
+// uuid replaces value with a random, version 4 UUID
+func (g *generator) uuid(value string) (string, error) {
+	b := make([]byte, 16)
+	if err := g.read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+	return uuid.UUID(b).String(), nil
+}
+
+// hex replaces value with a random secret of n hex digits
+func (g *generator) hex(value string, n int) (string, error) {
+	if n <= 0 {
+		return "", fmt.Errorf("length must be positive")
+	}
+	b := make([]byte, (n+1)/2)
+	if err := g.read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b)[:n], nil
+}
+
+// secure draws on the operating system's cryptographically secure source
+var secure = &generator{r: rand.Reader}
+
+// Random appends a hyphen and 256 random bits in base 32 to value
+func Random(value string) (string, error) {
+	return secure.random(value)
+}
+
+// UUID replaces value with a random, version 4 UUID
+func UUID(value string) (string, error) {
+	return secure.uuid(value)
+}
+
+// Hex replaces value with a random secret of n hex digits e.g. hex(32)
+func Hex(value string, n int) (string, error) {
+	return secure.hex(value, n)
+}
+
+// SHA256 returns the hex encoded SHA-256 hash of value.  Unlike the random
+// formatters the result only depends on value.
+func SHA256(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Hash returns the first n hex digits of the SHA-256 hash of value, giving a
+// short identifier that is stable for a given value
+func Hash(value string, n int) (string, error) {
+	if n <= 0 || n > sha256.Size*2 {
+		return "", fmt.Errorf("length must be between 1 and %d", sha256.Size*2)
+	}
+	return SHA256(value)[:n], nil
+}
+
+// RandomFuncs returns the formatters that draw on a source of randomness,
+// reading from r rather than the operating system.  Pass them to Funcs with a
+// reader from NewSeededReader to make rendering reproducible.
+func RandomFuncs(r io.Reader) FuncMap {
+	g := &generator{r: r}
+	return FuncMap{
+		"random":          g.random,
+		"generate-random": g.random,
+		"uuid":            g.uuid,
+		"hex":             g.hex,
+	}
+}
+
+// NewSeededReader returns a deterministic stream of pseudo-random bytes; the
+// same seed always produces the same stream.  It is not suitable for secrets
+// that must be unpredictable.
+func NewSeededReader(seed string) io.Reader {
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	return math_rand.New(math_rand.NewSource(int64(h.Sum64())))
+}
@@ -0,0 +1,112 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"regexp"
+	"testing"
+)
+
+var uuidRe = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRandom(t *testing.T) {
+	Convey("When I #Random a string", t, func() {
+		result, err := Random("hello")
+		So(err, ShouldBeNil)
+		So(result, ShouldStartWith, "hello-")
+		So(len(result), ShouldBeGreaterThan, len("hello-"))
+
+		again, _ := Random("hello")
+		So(again, ShouldNotEqual, result)
+	})
+}
+
+func TestUUID(t *testing.T) {
+	Convey("#UUID generates version 4 UUIDs", t, func() {
+		value, err := UUID("ignored")
+		So(err, ShouldBeNil)
+		So(uuidRe.MatchString(value), ShouldBeTrue)
+	})
+}
+
+func TestHex(t *testing.T) {
+	Convey("#Hex generates secrets of the given length", t, func() {
+		for _, n := range []int{1, 7, 32, 64} {
+			value, err := Hex("", n)
+			So(err, ShouldBeNil)
+			So(len(value), ShouldEqual, n)
+			So(regexp.MustCompile(`^[0-9a-f]+$`).MatchString(value), ShouldBeTrue)
+		}
+
+		_, err := Hex("", 0)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestHash(t *testing.T) {
+	Convey("#SHA256 and #Hash only depend on the value", t, func() {
+		So(SHA256("hello"), ShouldEqual, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+
+		value, err := Hash("hello", 8)
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "2cf24dba")
+
+		_, err = Hash("hello", 65)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestRandomFuncs(t *testing.T) {
+	text := []byte(`$name;format="random"$ $id;format="uuid"$ $secret;format="hex(16)"$ $name;format="hash(6)"$`)
+	fields := map[string]string{"name": "app", "id": "", "secret": ""}
+
+	render := func(seed string) string {
+		value, err := Render(text, fields, RandomFuncs(NewSeededReader(seed)))
+		So(err, ShouldBeNil)
+		return string(value)
+	}
+
+	Convey("The same seed gives the same values", t, func() {
+		first := render("golden")
+		So(render("golden"), ShouldEqual, first)
+		So(render("other"), ShouldNotEqual, first)
+	})
+
+	Convey("A generator draws fresh values on each use", t, func() {
+		funcs := RandomFuncs(NewSeededReader("golden"))
+		a, err := Render([]byte(`$id;format="uuid"$`), fields, funcs)
+		So(err, ShouldBeNil)
+		b, err := Render([]byte(`$id;format="uuid"$`), fields, funcs)
+		So(err, ShouldBeNil)
+		So(uuidRe.Match(a), ShouldBeTrue)
+		So(bytes.Equal(a, b), ShouldBeFalse)
+	})
+
+	Convey("Without a seed values are unpredictable", t, func() {
+		a, _ := Render(text, fields)
+		b, _ := Render(text, fields)
+		So(string(a), ShouldNotEqual, string(b))
+	})
+}
@@ -0,0 +1,215 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FuncMap is the type of the map defining the mapping from names to
+// formatters.  Each formatter must be a function that takes the value of a
+// field as a string and returns the formatted string, optionally along with an
+// error.  A non-nil error stops execution of the template.  Formatters may take
+// further string or int parameters, which are supplied as arguments in the long
+// format e.g. $name;format="truncate(20)"$ and checked when a template is parsed.
+type FuncMap map[string]interface{}
+
+// formatterRegistry is a set of named formatters.  It is safe for concurrent
+// use.
+type formatterRegistry struct {
+	mu    sync.RWMutex
+	funcs FuncMap
+}
+
+// newFormatterRegistry returns an empty registry
+func newFormatterRegistry() *formatterRegistry {
+	return &formatterRegistry{funcs: FuncMap{}}
+}
+
+// Register adds the formatter fn under name, replacing any existing formatter
+// of that name.
+func (r *formatterRegistry) Register(name string, fn interface{}) error {
+	if err := checkFormatter(name, fn); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs[name] = fn
+	return nil
+}
+
+// Lookup returns the formatter registered under name
+func (r *formatterRegistry) Lookup(name string) (interface{}, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.funcs[name]
+	return fn, ok
+}
+
+// Formatters returns the sorted names of the registered formatters
+func (r *formatterRegistry) Formatters() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := []string{}
+	for name := range r.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registry holds the formatters available to every template
+var registry = newFormatterRegistry()
+
+func init() {
+	for name, fn := range builtins {
+		if err := registry.Register(name, fn); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register adds a formatter available to every template
+func Register(name string, fn interface{}) error {
+	return registry.Register(name, fn)
+}
+
+// Lookup returns the formatter available to every template under name
+func Lookup(name string) (interface{}, bool) {
+	return registry.Lookup(name)
+}
+
+// Formatters returns the sorted names of the formatters available to every
+// template
+func Formatters() []string {
+	return registry.Formatters()
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// checkFormatter verifies that name can be referenced from a template and
+// that fn has the signature of a formatter
+func checkFormatter(name string, fn interface{}) error {
+	if name == "" || strings.HasPrefix(name, "-") || strings.Contains(name, "__") {
+		return fmt.Errorf("template: invalid formatter name %q", name)
+	}
+	for _, r := range name {
+		if !isIdentRune(r) && r != '-' {
+			return fmt.Errorf("template: invalid formatter name %q", name)
+		}
+	}
+
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("template: formatter %q is not a function", name)
+	}
+	t := v.Type()
+	if t.NumIn() == 0 || t.In(0).Kind() != reflect.String || t.IsVariadic() {
+		return fmt.Errorf("template: formatter %q must take the value as a string argument", name)
+	}
+	for i := 1; i < t.NumIn(); i++ {
+		if kind := t.In(i).Kind(); kind != reflect.String && kind != reflect.Int {
+			return fmt.Errorf("template: formatter %q parameter %d must be a string or an int", name, i)
+		}
+	}
+	switch {
+	case t.NumOut() == 1 && t.Out(0).Kind() == reflect.String:
+	case t.NumOut() == 2 && t.Out(0).Kind() == reflect.String && t.Out(1) == errorType:
+	default:
+		return fmt.Errorf("template: formatter %q must return a string and optionally an error", name)
+	}
+	return nil
+}
+
+// checkArgs verifies that args match the parameters of the formatter fn
+// following the value, and converts them to the parameter types
+func checkArgs(fn interface{}, args []arg) ([]reflect.Value, error) {
+	t := reflect.TypeOf(fn)
+	if want := t.NumIn() - 1; len(args) != want {
+		return nil, fmt.Errorf("expected %d %s, got %d", want, plural(want, "argument"), len(args))
+	}
+
+	values := []reflect.Value{}
+	for index, a := range args {
+		param := t.In(index + 1)
+		v := reflect.ValueOf(a.value)
+		if text, ok := a.value.(string); ok && a.bare && param.Kind() == reflect.Int {
+			n, err := strconv.Atoi(text)
+			if err != nil {
+				return nil, fmt.Errorf("argument %d must be a number, got %q", index+1, text)
+			}
+			v = reflect.ValueOf(n)
+		}
+		if v.Kind() != param.Kind() {
+			return nil, fmt.Errorf("argument %d must be %s, got %s", index+1, kindName(param.Kind()), kindName(v.Kind()))
+		}
+		values = append(values, v.Convert(param))
+	}
+	return values, nil
+}
+
+func kindName(kind reflect.Kind) string {
+	if kind == reflect.Int {
+		return "a number"
+	}
+	return "a string"
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return noun
+	}
+	return noun + "s"
+}
+
+// formatterCall is a formatter along with the arguments it was given
+type formatterCall struct {
+	fn   interface{}
+	args []reflect.Value
+}
+
+// call applies the formatter to value
+func (c formatterCall) call(value string) (string, error) {
+	if len(c.args) == 0 {
+		switch f := c.fn.(type) {
+		case func(string) string:
+			return f(value), nil
+		case func(string) (string, error):
+			return f(value)
+		}
+	}
+
+	fn := reflect.ValueOf(c.fn)
+	in := append([]reflect.Value{reflect.ValueOf(value).Convert(fn.Type().In(0))}, c.args...)
+	results := fn.Call(in)
+	if len(results) == 2 && !results[1].IsNil() {
+		return "", results[1].Interface().(error)
+	}
+	return results[0].String(), nil
+}
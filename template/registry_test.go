@@ -0,0 +1,146 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type label string
+
+func TestRegistry(t *testing.T) {
+	Convey("Given an empty registry", t, func() {
+		r := newFormatterRegistry()
+
+		Convey("Formatters can be registered, looked up and listed", func() {
+			So(r.Register("java-ident", strings.ToLower), ShouldBeNil)
+			So(r.Register("dns-label", func(value string) (string, error) { return value, nil }), ShouldBeNil)
+
+			fn, ok := r.Lookup("java-ident")
+			So(ok, ShouldBeTrue)
+			So(fn, ShouldNotBeNil)
+
+			_, ok = r.Lookup("gopkg")
+			So(ok, ShouldBeFalse)
+
+			So(r.Formatters(), ShouldResemble, []string{"dns-label", "java-ident"})
+		})
+
+		Convey("Invalid names are rejected", func() {
+			for _, name := range []string{"", "-x", "a__b", "a b", "a,b", `a"b`} {
+				So(r.Register(name, strings.ToLower), ShouldNotBeNil)
+			}
+		})
+
+		Convey("Functions without the formatter signature are rejected", func() {
+			for _, fn := range []interface{}{
+				nil,
+				"upper",
+				(func(string) string)(nil),
+				func() string { return "" },
+				func(int) string { return "" },
+				func(string, float64) string { return "" },
+				func(string) int { return 0 },
+				func(string) (string, string) { return "", "" },
+				func(...string) string { return "" },
+			} {
+				So(r.Register("bad", fn), ShouldNotBeNil)
+			}
+		})
+
+		Convey("Concurrent use is safe", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					name := fmt.Sprintf("f%d", i)
+					r.Register(name, strings.ToUpper)
+					r.Lookup(name)
+					r.Formatters()
+				}(i)
+			}
+			wg.Wait()
+			So(len(r.Formatters()), ShouldEqual, 20)
+		})
+	})
+}
+
+func TestRegisteredFormatters(t *testing.T) {
+	Convey("The built in formatters are registered", t, func() {
+		_, ok := Lookup("upper")
+		So(ok, ShouldBeTrue)
+		So(Formatters(), ShouldContain, "package-dir")
+	})
+
+	Convey("Registered formatters are available to every template", t, func() {
+		So(Register("gopkg", func(value string) string {
+			return strings.Replace(strings.ToLower(value), "-", "", -1)
+		}), ShouldBeNil)
+
+		value, err := Render([]byte(`package $name__gopkg$`), map[string]string{"name": "Order-Service"})
+		So(err, ShouldBeNil)
+		So(string(value), ShouldEqual, "package orderservice")
+	})
+}
+
+func TestFuncs(t *testing.T) {
+	shout := FuncMap{"shout": func(value string) string { return strings.ToUpper(value) + "!" }}
+	fields := map[string]string{"name": "hello"}
+
+	Convey("Formatters passed to #Render are available to that call only", t, func() {
+		value, err := Render([]byte(`$name__shout$`), fields, shout)
+		So(err, ShouldBeNil)
+		So(string(value), ShouldEqual, "HELLO!")
+
+		_, err = Render([]byte(`$name__shout$`), fields)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Template formatters take precedence over registered ones", t, func() {
+		tmpl, err := New("test").Funcs(FuncMap{"upper": func(value label) label { return "up:" + value }}).Parse([]byte(`$name__upper$`))
+		So(err, ShouldBeNil)
+
+		value, err := tmpl.render(fields)
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "up:hello")
+	})
+
+	Convey("Errors returned by a formatter stop execution", t, func() {
+		failing := FuncMap{"dns-label": func(value string) (string, error) {
+			return "", errors.New("not a valid DNS label")
+		}}
+		_, err := Render([]byte("x\n  $name;format=\"dns-label\"$"), fields, failing)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `template: template:2:3: formatter "dns-label": not a valid DNS label in $name;format="dns-label"$`)
+	})
+
+	Convey("Invalid formatters panic", t, func() {
+		So(func() { New("test").Funcs(FuncMap{"bad": 42}) }, ShouldPanic)
+	})
+}
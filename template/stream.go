@@ -0,0 +1,202 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// size of the buffer used to read templates being streamed.  Lines without
+// expressions are streamed through in pieces no larger than this, however long
+// they are.
+const streamBufferSize = 64 * 1024
+
+// Stream reads a giter8 template from r and writes its output for data to w
+// without holding the whole template in memory.  Text is parsed and executed a
+// segment at a time: lines without expressions are copied straight through,
+// and only lines within a $if$ or $for$ block are held until the block ends.
+// Positions in errors are relative to the whole template.  t needn't be
+// parsed; its name, formatters, options and loader are used.
+//
+// Since output is written as it is produced, w may have received part of the
+// output when an error is returned.  With missingkey=error the undefined
+// fields of the whole template are reported once it has been read.
+func (t *Template) Stream(w io.Writer, r io.Reader, data interface{}) error {
+	s := &stream{tmpl: t, wr: w, data: data, line: 1}
+
+	in := bufio.NewReaderSize(r, streamBufferSize)
+	for {
+		chunk, err := in.ReadSlice('\n')
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return err
+		}
+		if err := s.read(chunk, err != bufio.ErrBufferFull); err != nil {
+			return err
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+	if len(s.undefined) > 0 {
+		return &UndefinedError{Name: t.name, Fields: s.undefined}
+	}
+	return nil
+}
+
+// RenderStream reads a giter8 template from r and writes its output for data
+// to w.  Formatters in funcs are available to the template in addition to the
+// registered formatters.
+func RenderStream(w io.Writer, r io.Reader, data interface{}, funcs ...FuncMap) error {
+	t := New("template")
+	for _, funcMap := range funcs {
+		t.Funcs(funcMap)
+	}
+	return t.Stream(w, r, data)
+}
+
+// stream holds the state of a template being streamed
+type stream struct {
+	tmpl      *Template
+	wr        io.Writer
+	data      interface{}
+	undefined []UndefinedField
+
+	segment   []byte // lines held until the blocks they open are closed
+	lineStart int    // offset in segment of the line being read
+	depth     int    // number of blocks open in segment
+	line      int    // line number of the start of segment
+	col       int    // number of runes of the current line already written
+}
+
+// read handles the next chunk of input.  complete is set when the chunk ends
+// a line, or the input.
+func (s *stream) read(chunk []byte, complete bool) error {
+	// plain text is written as is, unless it ends with a backslash that may
+	// escape a $ in the next chunk
+	plain := bytes.IndexByte(chunk, delim) < 0 && (complete || !bytes.HasSuffix(chunk, []byte{escape}))
+	if len(s.segment) == 0 && plain {
+		if _, err := s.wr.Write(chunk); err != nil {
+			return err
+		}
+		s.advance(chunk)
+		return nil
+	}
+
+	s.segment = append(s.segment, chunk...)
+	if !complete {
+		return nil
+	}
+
+	s.depth += blockDepth(s.segment[s.lineStart:])
+	s.lineStart = len(s.segment)
+	if s.depth > 0 {
+		return nil
+	}
+	return s.flush()
+}
+
+// advance moves the position past text that has been written
+func (s *stream) advance(text []byte) {
+	if lines := bytes.Count(text, []byte{'\n'}); lines > 0 {
+		s.line += lines
+		s.col = utf8.RuneCount(text[bytes.LastIndexByte(text, '\n')+1:])
+		return
+	}
+	s.col += utf8.RuneCount(text)
+}
+
+// flush parses and executes the pending segment
+func (s *stream) flush() error {
+	if len(s.segment) == 0 {
+		return nil
+	}
+	text := s.segment
+	s.segment, s.lineStart, s.depth = nil, 0, 0
+
+	root, err := parseSegment(s.tmpl.name, text, s.tmpl.funcs, s.col > 0)
+	if err != nil {
+		return s.relocate(err)
+	}
+
+	segment := &Template{name: s.tmpl.name, text: text, lines: lineStarts(text), root: root, funcs: s.tmpl.funcs, option: s.tmpl.option, partials: s.tmpl.partials}
+	st := &state{tmpl: segment, wr: s.wr, data: s.data}
+	if err := st.walk(root); err != nil {
+		return s.relocate(err)
+	}
+	for _, f := range st.undefined {
+		if f.Template == "" {
+			f.Line, f.Col = s.position(f.Line, f.Col)
+		}
+		s.undefined = append(s.undefined, f)
+	}
+
+	s.advance(text)
+	return nil
+}
+
+// relocate moves the position of an error in the segment to its position in
+// the whole template
+func (s *stream) relocate(err error) error {
+	if e, ok := err.(*Error); ok && e.Name == s.tmpl.name {
+		e.Line, e.Col = s.position(e.Line, e.Col)
+	}
+	return err
+}
+
+// position converts a position in the segment to one in the whole template
+func (s *stream) position(line, col int) (int, int) {
+	if line == 1 {
+		col += s.col
+	}
+	return line + s.line - 1, col
+}
+
+// blockDepth returns the change in the number of open blocks caused by the
+// $if$, $for$, $endif$ and $endfor$ keywords in line
+func blockDepth(line []byte) int {
+	depth := 0
+	items := lex(line)
+	for index := 0; index+2 < len(items); index++ {
+		if items[index].typ != itemLeftDelim || items[index+1].typ != itemIdentifier {
+			continue
+		}
+		switch following := items[index+2].typ; items[index+1].val {
+		case "if", "for":
+			if following == itemLeftParen {
+				depth++
+			}
+		case "endif", "endfor":
+			if following == itemRightDelim {
+				depth--
+			}
+		}
+	}
+	return depth
+}
@@ -0,0 +1,122 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"bytes"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	fields := map[string]string{"name": "shop", "docker": "yes", "modules": "api,cli"}
+
+	stream := func(text string, opt string) (string, error) {
+		buffer := bytes.NewBuffer([]byte{})
+		err := New("test").Option(opt).Stream(buffer, strings.NewReader(text), fields)
+		return buffer.String(), err
+	}
+
+	Convey("Streaming produces the same output as rendering", t, func() {
+		for _, text := range []string{
+			"",
+			"plain text without a trailing newline",
+			"hello $name;format=\"upper\"$\n\\$HOME\n",
+			"a\n  $if(docker.truthy)$\n  docker\n  $else$\n  none\n  $endif$\nb\n",
+			"$for(m in modules)$\n- $m$ of $name$\n$endfor$\n$if(docker)$yes$endif$ $name$\n",
+			"$if(docker)$\n$for(m in modules)$\n$if(m)$$m$$endif$\n$endfor$\n$endif$",
+		} {
+			expected, err := Render([]byte(text), fields)
+			So(err, ShouldBeNil)
+
+			value, err := stream(text, "missingkey=default")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, string(expected))
+		}
+	})
+
+	Convey("Lines longer than the buffer are streamed", t, func() {
+		long := strings.Repeat("x", streamBufferSize+10)
+
+		value, err := stream(long+"\n"+long+"$name$\n", "missingkey=default")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, long+"\n"+long+"shop\n")
+
+		// the escape falls at the end of the first chunk
+		escaped := strings.Repeat("x", streamBufferSize-1) + "\\$name"
+		value, err = stream(escaped, "missingkey=default")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, strings.Repeat("x", streamBufferSize-1)+"$name")
+	})
+
+	Convey("Error positions are relative to the whole template", t, func() {
+		_, err := stream("a\n$if(docker)$\nb\n$endif$\nc $name__uper$\n", "missingkey=default")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `template: test:5:10: unknown formatter "uper" in $name__uper$; did you mean "upper"?`)
+
+		_, err = stream("a\n$if(docker)$\nb\n", "missingkey=default")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `template: test:2:1: unclosed $if$; missing $endif$ in $if(docker)$`)
+
+		_, err = stream(strings.Repeat("x", streamBufferSize)+`$nmae;format="upper$`, "missingkey=default")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "template: test:1:65550: unterminated quoted string")
+	})
+
+	Convey("Undefined fields are reported for the whole template", t, func() {
+		value, err := stream("$nmae$\nok\n$for(m in modules)$$m$$tilte$$endfor$\n", "missingkey=error")
+		So(value, ShouldEqual, "\nok\napicli\n")
+		So(err, ShouldNotBeNil)
+
+		undefined, ok := err.(*UndefinedError)
+		So(ok, ShouldBeTrue)
+		So(len(undefined.Fields), ShouldEqual, 2)
+		So(undefined.Fields[0].Line, ShouldEqual, 1)
+		So(undefined.Fields[1].Line, ShouldEqual, 3)
+		So(undefined.Fields[1].Col, ShouldEqual, 23)
+	})
+
+	Convey("RenderStream renders with the registered formatters", t, func() {
+		buffer := bytes.NewBuffer([]byte{})
+		So(RenderStream(buffer, strings.NewReader(`$name__upper$`), fields), ShouldBeNil)
+		So(buffer.String(), ShouldEqual, "SHOP")
+	})
+}
+
+func TestBlockDepth(t *testing.T) {
+	Convey("Block keywords change the depth", t, func() {
+		for line, depth := range map[string]int{
+			"plain":                        0,
+			"$if(a)$":                      1,
+			"$for(m in ms)$ $if(a)$":       2,
+			"$if(a)$x$endif$":              0,
+			"$endfor$ $endif$":             -2,
+			"$elseif(a)$ $else$":           0,
+			`\$if(a)\$`:                    0,
+			"$if$ $endif__upper$ $for(a)$": 1,
+		} {
+			So(blockDepth([]byte(line)), ShouldEqual, depth)
+		}
+	})
+}
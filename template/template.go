@@ -25,27 +25,203 @@ package template
 import (
 	"bytes"
 	"fmt"
-	"regexp"
-	text_template "text/template"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
-var (
-	// $name__filter1__filter2$
-	shortFormat = regexp.MustCompile(`\$([a-zA-Z0-9]+(__[a-zA-Z0-9]+)*)\$`)
+// Template is a parsed giter8 template.  Literal text is emitted verbatim so
+// content that happens to look like a Go template, e.g. {{ .Values.name }} in a
+// Helm chart, passes through untouched; only $...$ expressions are evaluated.
+//
+// A template is compiled once by Parse and may then be executed any number of
+// times, including concurrently, so text rendered repeatedly need not be
+// parsed again.
+type Template struct {
+	name     string
+	text     []byte // original source, used to report positions
+	lines    []int  // offsets of the start of each line of text
+	root     *ListNode
+	funcs    FuncMap // formatters specific to this template
+	option   option
+	partials *Partials // partials for $include$; nil if includes are not supported
+}
 
-	// $name;format="filter1,filter2"$
-	longFormat = regexp.MustCompile(`\$((\w+);format="([^"]+)")\$`)
-)
+// Loader reads the partial template with the given name, as written in
+// $include("partials/header.txt")$
+type Loader func(name string) ([]byte, error)
+
+// DirLoader returns a Loader that reads partials relative to the root
+// directory.  Names may not refer to files outside of root.
+func DirLoader(root string) Loader {
+	return func(name string) ([]byte, error) {
+		path := filepath.Clean(filepath.FromSlash(name))
+		if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is outside of the template", name)
+		}
+		return ioutil.ReadFile(filepath.Join(root, path))
+	}
+}
+
+// Partials reads and parses the partials named by $include$ expressions,
+// keeping each one so it's parsed once however many times it's included, and by
+// however many templates.  Templates sharing Partials should have the same
+// formatters, since a partial is parsed with those of the template that first
+// includes it.  Partials is safe for concurrent use.
+type Partials struct {
+	load   Loader
+	mu     sync.Mutex
+	parsed map[string]*partial
+}
+
+// partial is the result of reading and parsing a partial
+type partial struct {
+	tmpl    *Template
+	loadErr error // the partial couldn't be read
+	err     error // the partial couldn't be parsed
+}
+
+// NewPartials returns Partials that reads partials with load.
+func NewPartials(load Loader) *Partials {
+	return &Partials{load: load, parsed: map[string]*partial{}}
+}
+
+// get returns the partial with the given name, reading and parsing it with
+// funcs the first time it's asked for
+func (p *Partials) get(name string, funcs FuncMap) *partial {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.parsed[name]; ok {
+		return cached
+	}
+
+	result := &partial{}
+	if text, err := p.load(name); err != nil {
+		result.loadErr = err
+	} else {
+		result.tmpl, result.err = (&Template{name: name, funcs: funcs, partials: p}).Parse(text)
+	}
+	p.parsed[name] = result
+	return result
+}
+
+// New allocates a new, empty template with the given name.  The name is used
+// when reporting errors.
+func New(name string) *Template {
+	return &Template{name: name}
+}
+
+// Name returns the name of the template.
+func (t *Template) Name() string {
+	return t.name
+}
 
-// converts data on the other end of the reader to a golang template
-func Parse(text []byte) (*text_template.Template, error) {
-	text = transform(text)
+// Funcs adds the formatters in funcMap to the template, taking precedence over
+// registered formatters of the same name.  It must be called before the
+// template is parsed and panics if a value in the map is not a valid formatter.
+func (t *Template) Funcs(funcMap FuncMap) *Template {
+	if t.funcs == nil {
+		t.funcs = FuncMap{}
+	}
+	for name, fn := range funcMap {
+		if err := checkFormatter(name, fn); err != nil {
+			panic(err)
+		}
+		t.funcs[name] = fn
+	}
+	return t
+}
+
+// Includes sets the Loader used to read the partials named by $include$
+// expressions.  Partials are rendered with the same formatters, options and
+// data as the template including them, and are parsed once however many times
+// they're executed.
+func (t *Template) Includes(load Loader) *Template {
+	if load == nil {
+		t.partials = nil
+		return t
+	}
+	return t.IncludesFrom(NewPartials(load))
+}
 
-	return text_template.New("template").Funcs(funcMap).Parse(string(text))
+// IncludesFrom is like Includes, but shares the partials, and the work of
+// parsing them, with the other templates including from partials.
+func (t *Template) IncludesFrom(partials *Partials) *Template {
+	t.partials = partials
+	return t
 }
 
-func Render(text []byte, data interface{}) ([]byte, error) {
-	t, err := Parse(text)
+// Parse parses text as a giter8 template body for t.
+func (t *Template) Parse(text []byte) (*Template, error) {
+	root, err := parse(t.name, text, t.funcs)
+	if err != nil {
+		return nil, err
+	}
+	t.text = text
+	t.lines = lineStarts(text)
+	t.root = root
+	return t, nil
+}
+
+// Execute applies the template to data, writing the output to w.  data is
+// expected to be a map[string]string or map[string]interface{} of fields.
+func (t *Template) Execute(w io.Writer, data interface{}) error {
+	if t.root == nil {
+		return fmt.Errorf("template: %s: template has not been parsed", t.name)
+	}
+	s := &state{tmpl: t, wr: w, data: data}
+	if err := s.walk(t.root); err != nil {
+		return err
+	}
+	if len(s.undefined) > 0 {
+		return &UndefinedError{Name: t.name, Fields: s.undefined}
+	}
+	return nil
+}
+
+// Expansion is one output of a template expanded by Expand
+type Expansion struct {
+	Text     []byte
+	Bindings map[string]string // loop variables bound to produce Text
+}
+
+// Expand executes the template once for each item of each $for$ loop at its
+// top level, rather than concatenating the iterations.  Loops directly within
+// the body of an expanded loop are expanded in turn, so a path segment such as
+// $for(m in modules)$$m$$endfor$ yields one name per module.  A template without
+// loops expands to its ordinary output.
+func (t *Template) Expand(data interface{}) ([]Expansion, error) {
+	if t.root == nil {
+		return nil, fmt.Errorf("template: %s: template has not been parsed", t.name)
+	}
+	s := &state{tmpl: t, data: data}
+	expansions := []Expansion{}
+	if err := s.expand(t.root.Nodes, map[string]string{}, &expansions); err != nil {
+		return nil, err
+	}
+	if len(s.undefined) > 0 {
+		return nil, &UndefinedError{Name: t.name, Fields: s.undefined}
+	}
+	return expansions, nil
+}
+
+// Parse converts giter8 text into a template.  Formatters in funcs are available
+// to the template in addition to the registered formatters.
+func Parse(text []byte, funcs ...FuncMap) (*Template, error) {
+	t := New("template")
+	for _, funcMap := range funcs {
+		t.Funcs(funcMap)
+	}
+	return t.Parse(text)
+}
+
+func Render(text []byte, data interface{}, funcs ...FuncMap) ([]byte, error) {
+	t, err := Parse(text, funcs...)
 	if err != nil {
 		return nil, err
 	}
@@ -59,41 +235,350 @@ func Render(text []byte, data interface{}) ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
-// transform templates in the short format to go templates e.g. name__filter1__filter2
-func transformShort(text []byte) []byte {
-	results := text
-	matches := shortFormat.FindAllSubmatch(results, -1)
-	for _, match := range matches {
-		segments := bytes.Split(match[1], []byte("__"))
-		macro := fmt.Sprintf("{{ .%s }}", bytes.Join(segments, []byte(" | ")))
-		results = bytes.Replace(results, match[0], []byte(macro), -1)
+// state represents the state of an execution.
+type state struct {
+	tmpl      *Template
+	wr        io.Writer
+	data      interface{}
+	undefined []UndefinedField // collected when missingkey=error
+	recorded  map[string]bool  // positions of the undefined fields, to record each once
+	including []string         // names of the templates including this one, outermost first
+}
+
+func (s *state) walk(node Node) error {
+	switch n := node.(type) {
+	case *ListNode:
+		for _, child := range n.Nodes {
+			if err := s.walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *TextNode:
+		_, err := s.wr.Write(n.Text)
+		return err
+	case *FieldNode:
+		value, ok := lookup(s.data, n.Name)
+		if !ok && !n.defaulted() {
+			switch s.tmpl.option.missingKey {
+			case mapError:
+				s.undefine(n.Pos, n.Name, n.String())
+				return nil
+			case mapKeep:
+				_, err := io.WriteString(s.wr, n.String())
+				return err
+			}
+		}
+		for index, c := range n.calls {
+			formatted, err := c.call(value)
+			if err != nil {
+				return s.errorf(n, "formatter %q: %s", n.Formatters[index], err)
+			}
+			value = formatted
+		}
+		_, err := io.WriteString(s.wr, value)
+		return err
+	case *ForNode:
+		outer := s.data
+		defer func() { s.data = outer }()
+		for _, item := range s.items(n) {
+			s.data = &scope{parent: outer, name: n.Var, value: item}
+			if err := s.walk(n.Body); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *IncludeNode:
+		return s.include(n)
+	case *IfNode:
+		for _, branch := range n.Branches {
+			if s.test(branch.Cond) {
+				return s.walk(branch.List)
+			}
+		}
+		if n.Else != nil {
+			return s.walk(n.Else)
+		}
+		return nil
+	default:
+		return fmt.Errorf("template: %s: unknown node %T", s.tmpl.name, node)
 	}
+}
+
+// expand renders nodes once for each combination of items of the loops found at
+// its top level, appending the results to expansions
+func (s *state) expand(nodes []Node, bindings map[string]string, expansions *[]Expansion) error {
+	for index, node := range nodes {
+		loop, ok := node.(*ForNode)
+		if !ok {
+			continue
+		}
+
+		outer := s.data
+		defer func() { s.data = outer }()
+		for _, item := range s.items(loop) {
+			s.data = &scope{parent: outer, name: loop.Var, value: item}
+
+			bound := map[string]string{loop.Var: item}
+			for name, value := range bindings {
+				if name != loop.Var {
+					bound[name] = value
+				}
+			}
 
-	return results
+			// the loop's body takes the place of the loop itself
+			expanded := append([]Node{}, nodes[:index]...)
+			expanded = append(expanded, loop.Body.Nodes...)
+			expanded = append(expanded, nodes[index+1:]...)
+			if err := s.expand(expanded, bound, expansions); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+	s.wr = buffer
+	if err := s.walk(&ListNode{Nodes: nodes}); err != nil {
+		return err
+	}
+	*expansions = append(*expansions, Expansion{Text: buffer.Bytes(), Bindings: bindings})
+	return nil
+}
+
+// items returns the items a loop iterates over
+func (s *state) items(n *ForNode) []string {
+	items, ok := list(s.data, n.List)
+	if !ok && s.tmpl.option.missingKey == mapError {
+		s.undefine(n.Pos, n.List, exprAt(s.tmpl.text, int(n.Pos)))
+	}
+	return items
+}
+
+// include renders the partial named by n in place, with the current data
+func (s *state) include(n *IncludeNode) error {
+	if s.tmpl.partials == nil {
+		return s.errorf(n, "includes are not supported by this template")
+	}
+
+	including := append(append([]string{}, s.including...), s.tmpl.name)
+	for index, name := range including {
+		if name == n.Name {
+			cycle := append(including[index:], n.Name)
+			return s.errorf(n, "include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	cached := s.tmpl.partials.get(n.Name, s.tmpl.funcs)
+	if cached.loadErr != nil {
+		return s.errorf(n, "%s", cached.loadErr)
+	}
+	if cached.err != nil {
+		return cached.err
+	}
+	// the partial is executed with the options of the template including it
+	included := *cached.tmpl
+	included.option = s.tmpl.option
+
+	child := &state{tmpl: &included, wr: s.wr, data: s.data, including: including}
+	if err := child.walk(included.root); err != nil {
+		return err
+	}
+
+	// undefined references are reported against the partial holding them
+	for _, f := range child.undefined {
+		if f.Template == "" {
+			f.Template = included.name
+		}
+		s.addUndefined(f)
+	}
+	return nil
+}
+
+// errorf returns an error located at the node
+func (s *state) errorf(n Node, format string, args ...interface{}) error {
+	line, col := s.tmpl.position(int(n.Position()))
+	return &Error{
+		Name:    s.tmpl.name,
+		Line:    line,
+		Col:     col,
+		Expr:    n.String(),
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// undefine records a reference to an undefined field.  References within loops
+// are only recorded once.
+func (s *state) undefine(pos Pos, name, expr string) {
+	line, col := s.tmpl.position(int(pos))
+	f := UndefinedField{
+		Name:       name,
+		Expr:       expr,
+		Line:       line,
+		Col:        col,
+		Suggestion: suggest(name, keys(s.data)),
+	}
+	s.addUndefined(f)
+}
+
+// addUndefined records the reference unless it has already been recorded
+func (s *state) addUndefined(f UndefinedField) {
+	key := fmt.Sprintf("%s:%d:%d", f.Template, f.Line, f.Col)
+	if s.recorded == nil {
+		s.recorded = map[string]bool{}
+	}
+	if !s.recorded[key] {
+		s.recorded[key] = true
+		s.undefined = append(s.undefined, f)
+	}
 }
 
-// transform templates in the long format to go templates e.g. name;filter="lower,snake"
-func transformLong(text []byte) []byte {
-	results := text
-	matches := longFormat.FindAllSubmatch(results, -1)
-	for _, match := range matches {
-		field := match[2]
-		filters := match[3]
+// position converts a byte offset in the template into a 1-based line and
+// column without rescanning the text before the line
+func (t *Template) position(pos int) (line, col int) {
+	index := sort.SearchInts(t.lines, pos+1) - 1
+	return index + 1, utf8.RuneCount(t.text[t.lines[index]:pos]) + 1
+}
 
-		segments := [][]byte{field}
-		segments = append(segments, bytes.Split(filters, []byte(","))...)
+// lineStarts returns the offsets of the start of each line of text
+func lineStarts(text []byte) []int {
+	starts := []int{0}
+	for index, b := range text {
+		if b == '\n' {
+			starts = append(starts, index+1)
+		}
+	}
+	return starts
+}
+
+// test evaluates the condition of an $if$ or $elseif$ branch
+func (s *state) test(cond *Condition) bool {
+	value, ok := lookup(s.data, cond.Name)
+	result := ok && value != ""
+	if cond.Truthy {
+		result = Truthy(value)
+	}
+	return result != cond.Not
+}
 
-		macro := fmt.Sprintf("{{ .%s }}", bytes.Join(segments, []byte(" | ")))
-		results = bytes.Replace(results, match[0], []byte(macro), -1)
+// Truthy reports whether value is one of the giter8 truthy values: y, yes or
+// true, ignoring case
+func Truthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "y", "yes", "true":
+		return true
+	default:
+		return false
 	}
+}
 
-	return results
+// scope binds a loop variable on top of the enclosing data
+type scope struct {
+	parent interface{}
+	name   string
+	value  string
 }
 
-// helper to combine both short and long transforms
-func transform(text []byte) []byte {
-	text = giter8ify(text)
-	text = transformShort(text)
-	text = transformLong(text)
-	return text
+// lookup finds the named field in data
+func lookup(data interface{}, name string) (string, bool) {
+	switch fields := data.(type) {
+	case *scope:
+		if name == fields.name {
+			return fields.value, true
+		}
+		return lookup(fields.parent, name)
+	case map[string]string:
+		value, ok := fields[name]
+		return value, ok
+	case map[string]interface{}:
+		value, ok := fields[name]
+		if !ok || value == nil {
+			return "", ok
+		}
+		if items, isList := value.([]string); isList {
+			return strings.Join(items, ","), true
+		}
+		return fmt.Sprint(value), true
+	default:
+		return "", false
+	}
+}
+
+// list finds the named list-valued field in data.  A []string is used as is;
+// any other value is split on commas, ignoring blank items, so a property such
+// as modules=api, worker, cli holds three items.
+func list(data interface{}, name string) ([]string, bool) {
+	if fields, ok := data.(map[string]interface{}); ok {
+		if items, isList := fields[name].([]string); isList {
+			return items, true
+		}
+	}
+
+	value, ok := lookup(data, name)
+	return splitItems(value), ok
+}
+
+// splitItems splits a list-valued field on commas, ignoring blank items
+func splitItems(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// keys returns the names of the fields in data
+func keys(data interface{}) []string {
+	names := []string{}
+	switch fields := data.(type) {
+	case *scope:
+		names = append(keys(fields.parent), fields.name)
+	case map[string]string:
+		for name := range fields {
+			names = append(names, name)
+		}
+	case map[string]interface{}:
+		for name := range fields {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// UndefinedField is a reference to a field that was not present in the data
+type UndefinedField struct {
+	Name       string // name of the field
+	Expr       string // the expression referencing it e.g. $nmae__upper$
+	Line, Col  int    // 1-based position of the expression
+	Suggestion string // a defined field with a similar name, if any
+	Template   string // the partial holding the reference, if it was included
+}
+
+// UndefinedError is returned by Execute when the missingkey=error option is
+// set and the template references fields that are not present in the data.
+type UndefinedError struct {
+	Name   string // name of the template
+	Fields []UndefinedField
+}
+
+func (e *UndefinedError) Error() string {
+	messages := []string{}
+	for _, f := range e.Fields {
+		name := e.Name
+		if f.Template != "" {
+			name = f.Template
+		}
+		err := &Error{
+			Name:       name,
+			Line:       f.Line,
+			Col:        f.Col,
+			Expr:       f.Expr,
+			Message:    fmt.Sprintf("undefined field %q", f.Name),
+			Suggestion: f.Suggestion,
+		}
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
 }
@@ -24,60 +24,15 @@ package template
 
 import (
 	"bytes"
+	"fmt"
 	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
-func TestShortFormat(t *testing.T) {
-	Convey("Given a short format template", t, func() {
-		template := `before_$hello__world__argle$_after`
-
-		Convey("When I find string matches with the shortFormat", func() {
-			matches := shortFormat.FindAllStringSubmatch(template, -1)
-
-			Convey("Then I expect the string interpolation to be found", func() {
-				So(len(matches), ShouldEqual, 1)
-				So(matches[0][0], ShouldEqual, `$hello__world__argle$`)
-				So(matches[0][1], ShouldEqual, `hello__world__argle`)
-			})
-		})
-
-		Convey("When I transform the template to a go template", func() {
-			text := transformShort([]byte(template))
-
-			Convey("And the content should be in go template format", func() {
-				So(string(text), ShouldEqual, "before_{{ .hello | world | argle }}_after")
-			})
-		})
-	})
-}
-
-func TestLongFormat(t *testing.T) {
-	Convey("Given a long format template", t, func() {
-		template := `before_$name;format="normalize,lower"$_after`
-
-		Convey("When I find string matches with the longFormat", func() {
-			matches := longFormat.FindAllStringSubmatch(template, -1)
-
-			Convey("Then I expect the string interpolation to be found", func() {
-				So(len(matches), ShouldEqual, 1)
-				So(matches[0][0], ShouldEqual, `$name;format="normalize,lower"$`)
-				So(matches[0][1], ShouldEqual, `name;format="normalize,lower"`)
-				So(matches[0][2], ShouldEqual, `name`)
-				So(matches[0][3], ShouldEqual, `normalize,lower`)
-			})
-		})
-
-		Convey("When I transform the template to a go template", func() {
-			text := transformLong([]byte(template))
-
-			Convey("And the content should be in go template format", func() {
-				So(string(text), ShouldEqual, "before_{{ .name | normalize | lower }}_after")
-			})
-		})
-	})
-}
-
 func TestParse(t *testing.T) {
 	Convey("Given the text of a template", t, func() {
 		text := []byte(`hello $name;format="lower"$`)
@@ -102,7 +57,7 @@ func TestParse(t *testing.T) {
 	})
 
 	Convey("Given an invalid template", t, func() {
-		text := []byte("{{ ")
+		text := []byte(`hello $name;format="upper$`)
 
 		Convey("When I #Parse the template", func() {
 			_, err := Parse(text)
@@ -112,6 +67,19 @@ func TestParse(t *testing.T) {
 			})
 		})
 	})
+
+	Convey("Given a template that uses an unknown formatter", t, func() {
+		text := []byte("line one\nhello $name__bogus$")
+
+		Convey("When I #Parse the template", func() {
+			_, err := New("README.md").Parse(text)
+
+			Convey("Then I expect the error to include the name and position", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, `template: README.md:2:14: unknown formatter "bogus" in $name__bogus$`)
+			})
+		})
+	})
 }
 
 func TestRender(t *testing.T) {
@@ -130,4 +98,501 @@ func TestRender(t *testing.T) {
 			})
 		})
 	})
+
+	Convey("Given a template containing go template syntax", t, func() {
+		text := []byte(`name: {{ .Values.name }} # $name$ {{- end }}`)
+
+		Convey("Then #Render leaves the go template syntax untouched", func() {
+			value, err := Render(text, map[string]string{"name": "chart"})
+			So(err, ShouldBeNil)
+			So(string(value), ShouldEqual, `name: {{ .Values.name }} # chart {{- end }}`)
+		})
+	})
+
+	Convey("Given a Helm template using $ variables", t, func() {
+		text := []byte(`{{- range $key, $value := .Values.env }}
+- name: {{ $key }}
+  value: {{ $value | quote }} # $name$
+{{- end }}
+image: {{ $.Values.image }}
+`)
+
+		Convey("Then #Render leaves the $ variables untouched", func() {
+			value, err := Render(text, map[string]string{"name": "chart"})
+			So(err, ShouldBeNil)
+			So(string(value), ShouldEqual, `{{- range $key, $value := .Values.env }}
+- name: {{ $key }}
+  value: {{ $value | quote }} # chart
+{{- end }}
+image: {{ $.Values.image }}
+`)
+		})
+	})
+
+	Convey("Given $ followed by a name and a dot or semicolon", t, func() {
+		text := []byte(`image: {{ $root.Values.image }}:{{ $root.Values.tag }}
+VERSION="$MAJOR.$MINOR"
+echo "$a.$b"
+val s = s"$a;$b" // $name$
+`)
+
+		Convey("Then #Render leaves them untouched", func() {
+			value, err := Render(text, map[string]string{"name": "world"})
+			So(err, ShouldBeNil)
+			So(string(value), ShouldEqual, `image: {{ $root.Values.image }}:{{ $root.Values.tag }}
+VERSION="$MAJOR.$MINOR"
+echo "$a.$b"
+val s = s"$a;$b" // world
+`)
+		})
+	})
+
+	Convey("Given a shell script using $VAR", t, func() {
+		text := []byte("#!/bin/sh\nexport PATH=$HOME/bin:$PATH\necho \"${GREETING:-hello} $name$, that's $5\"\n")
+
+		Convey("Then #Render leaves the shell variables untouched", func() {
+			value, err := Render(text, map[string]string{"name": "world"})
+			So(err, ShouldBeNil)
+			So(string(value), ShouldEqual, "#!/bin/sh\nexport PATH=$HOME/bin:$PATH\necho \"${GREETING:-hello} world, that's $5\"\n")
+		})
+	})
+
+	Convey("Given a template with an escaped $", t, func() {
+		text := []byte(`echo \$HOME $name$`)
+
+		Convey("Then #Render emits a literal $", func() {
+			value, err := Render(text, map[string]string{"name": "world"})
+			So(err, ShouldBeNil)
+			So(string(value), ShouldEqual, `echo $HOME world`)
+		})
+	})
+
+	Convey("Given a template that references an undefined field", t, func() {
+		text := []byte(`hello $name$`)
+
+		Convey("Then #Render emits an empty string", func() {
+			value, err := Render(text, map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(string(value), ShouldEqual, `hello `)
+		})
+	})
+}
+
+func TestRenderConditional(t *testing.T) {
+	text := []byte(`$if(docker.truthy)$docker$elseif(podman.truthy)$podman$else$none$endif$`)
+
+	Convey("Given a template with a conditional", t, func() {
+		for fields, expected := range map[[2]string]string{
+			{"yes", "no"}:  "docker",
+			{"Y", ""}:      "docker",
+			{"TRUE", "y"}:  "docker",
+			{"n", "true"}:  "podman",
+			{"false", ""}:  "none",
+			{"maybe", "n"}: "none",
+		} {
+			value, err := Render(text, map[string]string{"docker": fields[0], "podman": fields[1]})
+			So(err, ShouldBeNil)
+			So(string(value), ShouldEqual, expected)
+		}
+	})
+
+	Convey("Given a conditional that only tests whether a field is defined", t, func() {
+		text := []byte(`$if(license)$License: $license$$endif$$if(!license)$unlicensed$endif$`)
+
+		value, err := Render(text, map[string]string{"license": "MIT"})
+		So(err, ShouldBeNil)
+		So(string(value), ShouldEqual, "License: MIT")
+
+		value, err = Render(text, map[string]string{})
+		So(err, ShouldBeNil)
+		So(string(value), ShouldEqual, "unlicensed")
+	})
+}
+
+func TestTruthy(t *testing.T) {
+	Convey("#Truthy accepts the giter8 truthy values", t, func() {
+		for _, value := range []string{"y", "Y", "yes", "YES", "true", "True", " true "} {
+			So(Truthy(value), ShouldBeTrue)
+		}
+		for _, value := range []string{"", "n", "no", "false", "1", "on"} {
+			So(Truthy(value), ShouldBeFalse)
+		}
+	})
+}
+
+func TestMissingKey(t *testing.T) {
+	text := []byte("hello $nmae$\nbye $name;format=\"upper\"$ $title__upper$")
+	fields := map[string]string{"name": "world"}
+
+	execute := func(opt string) (string, error) {
+		template, err := New("README.md").Option(opt).Parse(text)
+		So(err, ShouldBeNil)
+
+		buffer := bytes.NewBuffer([]byte{})
+		err = template.Execute(buffer, fields)
+		return buffer.String(), err
+	}
+
+	Convey("By default undefined fields render as empty strings", t, func() {
+		value, err := execute("missingkey=default")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "hello \nbye WORLD ")
+	})
+
+	Convey("With missingkey=error every undefined field is reported", t, func() {
+		_, err := execute("missingkey=error")
+		So(err, ShouldNotBeNil)
+
+		undefined, ok := err.(*UndefinedError)
+		So(ok, ShouldBeTrue)
+		So(undefined.Fields, ShouldResemble, []UndefinedField{
+			{Name: "nmae", Expr: "$nmae$", Line: 1, Col: 7, Suggestion: "name"},
+			{Name: "title", Expr: "$title__upper$", Line: 2, Col: 27},
+		})
+		So(err.Error(), ShouldEqual, "template: README.md:1:7: undefined field \"nmae\" in $nmae$; did you mean \"name\"?\n"+
+			"template: README.md:2:27: undefined field \"title\" in $title__upper$")
+	})
+
+	Convey("With missingkey=keep the original expression is left untouched", t, func() {
+		value, err := execute("missingkey=keep")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "hello $nmae$\nbye WORLD $title__upper$")
+	})
+
+	Convey("Conditionals on undefined fields are not errors", t, func() {
+		template, err := New("test").Option("missingkey=error").Parse([]byte(`$if(docker.truthy)$yes$else$no$endif$`))
+		So(err, ShouldBeNil)
+
+		buffer := bytes.NewBuffer([]byte{})
+		So(template.Execute(buffer, fields), ShouldBeNil)
+		So(buffer.String(), ShouldEqual, "no")
+	})
+
+	Convey("Unknown options panic", t, func() {
+		So(func() { New("test").Option("missingkey=bogus") }, ShouldPanic)
+		So(func() { New("test").Option("") }, ShouldPanic)
+	})
+}
+
+// render executes the template against data, returning the output as a string
+func (t *Template) render(data interface{}) (string, error) {
+	buffer := bytes.NewBuffer([]byte{})
+	err := t.Execute(buffer, data)
+	return buffer.String(), err
+}
+
+func TestRenderArguments(t *testing.T) {
+	fields := map[string]string{"name": "My Cool-Service", "build": "7"}
+
+	Convey("Formatters in the long format can take arguments", t, func() {
+		for text, expected := range map[string]string{
+			`$name;format="truncate(7)"$`:                          "My Cool",
+			`$name;format="replace("-","_")"$`:                     "My Cool_Service",
+			`$name;format="replace(\"-\",\"_\")"$`:                 "My Cool_Service",
+			`$name;format="lower, replace(" ", ""), truncate(6)"$`: "mycool",
+			`$build;format="padLeft(4,"0")"$`:                      "0007",
+			`$build;format="padRight( 3 , "x" )"$`:                 "7xx",
+			`$missing;format="default("none"),upper"$`:             "NONE",
+			`$name;format="replace(",", ";")"$`:                    "My Cool-Service",
+			`$name;format="replace("(", ")")"$`:                    "My Cool-Service",
+		} {
+			tmpl, err := New("test").Option("missingkey=error").Parse([]byte(text))
+			So(err, ShouldBeNil)
+
+			value, err := tmpl.render(fields)
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, expected)
+		}
+	})
+
+	Convey("Arguments are checked when the template is parsed", t, func() {
+		for text, message := range map[string]string{
+			`$name;format="truncate"$`:        `template: test:1:15: formatter "truncate": expected 1 argument, got 0 in $name;format="truncate"$`,
+			`$name__truncate$`:                `template: test:1:8: formatter "truncate": expected 1 argument, got 0 in $name__truncate$`,
+			`$name;format="truncate("20")"$`:  `template: test:1:15: formatter "truncate": argument 1 must be a number, got a string in $name;format="truncate("20")"$`,
+			`$name;format="replace("-")"$`:    `template: test:1:15: formatter "replace": expected 2 arguments, got 1 in $name;format="replace("-")"$`,
+			`$name;format="upper(1)"$`:        `template: test:1:15: formatter "upper": expected 0 arguments, got 1 in $name;format="upper(1)"$`,
+			`$name;format="truncate(x)"$`:     `template: test:1:24: invalid argument "x"; expected a number or a quoted string in $name;format="truncate(x)"$`,
+			`$name;format="truncate(2"$`:      `template: test:1:14: unterminated quoted string in $name;format="truncate(2"$`,
+			`$name;format="lower,,upper"$`:    `template: test:1:21: missing formatter name in $name;format="lower,,upper"$`,
+			`$name;format="lower upper"$`:     `template: test:1:21: unexpected 'u' in format in $name;format="lower upper"$`,
+			`$name;format="replace("-,"_")"$`: `template: test:1:14: unterminated quoted string in $name;format="replace("-,"_")"$`,
+		} {
+			_, err := New("test").Parse([]byte(text))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, message)
+		}
+	})
+}
+
+func TestRenderDate(t *testing.T) {
+	fields := map[string]string{"now": "2026-03-07T09:05:00+01:00"}
+
+	Convey("A formatter taking one string can be written with a colon", t, func() {
+		for text, expected := range map[string]string{
+			`$now;format="date:2006-01-02"$`:             "2026-03-07",
+			`$now;format="date:15:04 MST-0700"$`:         "09:05 +0100+0100",
+			`$now;format="date:Jan 2,upper"$`:            "MAR 7",
+			`$now;format="date("Mon, Jan 2"),upper"$`:    "SAT, MAR 7",
+			`$now;format="date:Jan 2, 2006"$`:            "Mar 7, 2026",
+			`$now;format="date:Mon, Jan 2, 2006,upper"$`: "SAT, MAR 7, 2026",
+		} {
+			value, err := Render([]byte(text), fields)
+			So(err, ShouldBeNil)
+			So(string(value), ShouldEqual, expected)
+		}
+	})
+
+	Convey("A numeric argument written with a colon is converted", t, func() {
+		value, err := Render([]byte(`$name;format="truncate:5"$ $id;format="padLeft(4,"0")"$`), map[string]string{"name": "Order Service", "id": "7"})
+		So(err, ShouldBeNil)
+		So(string(value), ShouldEqual, "Order 0007")
+
+		_, err = New("test").Parse([]byte(`$name;format="truncate:five"$`))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `template: test:1:15: formatter "truncate": argument 1 must be a number, got "five" in $name;format="truncate:five"$`)
+	})
+}
+
+func TestRenderLoop(t *testing.T) {
+	fields := map[string]string{"modules": "api, worker,cli", "name": "shop"}
+
+	Convey("A loop renders its body once per item", t, func() {
+		value, err := Render([]byte(`$for(m in modules)$[$name$-$m;format="upper"$]$endfor$`), fields)
+		So(err, ShouldBeNil)
+		So(string(value), ShouldEqual, "[shop-API][shop-WORKER][shop-CLI]")
+	})
+
+	Convey("A loop on lines of its own does not leave blank lines behind", t, func() {
+		value, err := Render([]byte("modules:\n  $for(m in modules)$\n  - $m$\n  $endfor$\n"), fields)
+		So(err, ShouldBeNil)
+		So(string(value), ShouldEqual, "modules:\n  - api\n  - worker\n  - cli\n")
+	})
+
+	Convey("Loops nest and the loop variable shadows fields of the same name", t, func() {
+		data := map[string]interface{}{"envs": []string{"dev", "prod"}, "name": "a,b"}
+		value, err := Render([]byte(`$for(e in envs)$$for(name in name)$$e$.$name$ $endfor$$endfor$$name$`), data)
+		So(err, ShouldBeNil)
+		So(string(value), ShouldEqual, "dev.a dev.b prod.a prod.b a,b")
+	})
+
+	Convey("Loops over empty or undefined fields render nothing", t, func() {
+		value, err := Render([]byte(`<$for(m in empty)$$m$$endfor$$for(m in nope)$$m$$endfor$>`), map[string]string{"empty": " , "})
+		So(err, ShouldBeNil)
+		So(string(value), ShouldEqual, "<>")
+	})
+
+	Convey("With missingkey=error an undefined list is reported once", t, func() {
+		template, err := New("test").Option("missingkey=error").Parse([]byte(`$for(m in moduels)$$m$$nmae$$endfor$`))
+		So(err, ShouldBeNil)
+
+		_, err = template.render(fields)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "template: test:1:1: undefined field \"moduels\" in $for(m in moduels)$; did you mean \"modules\"?")
+
+		template, err = New("test").Option("missingkey=error").Parse([]byte(`$for(m in modules)$$m$$nmae$$endfor$`))
+		So(err, ShouldBeNil)
+
+		_, err = template.render(fields)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "template: test:1:23: undefined field \"nmae\" in $nmae$; did you mean \"name\"?")
+	})
+}
+
+func TestExpand(t *testing.T) {
+	fields := map[string]string{"modules": "api,worker", "envs": "dev,prod"}
+
+	expand := func(text string) []Expansion {
+		template, err := New("test").Parse([]byte(text))
+		So(err, ShouldBeNil)
+
+		expansions, err := template.Expand(fields)
+		So(err, ShouldBeNil)
+		return expansions
+	}
+
+	Convey("A top level loop expands into one output per item", t, func() {
+		So(expand(`$for(m in modules)$$m$$endfor$.go`), ShouldResemble, []Expansion{
+			{Text: []byte("api.go"), Bindings: map[string]string{"m": "api"}},
+			{Text: []byte("worker.go"), Bindings: map[string]string{"m": "worker"}},
+		})
+	})
+
+	Convey("Nested loops expand into every combination", t, func() {
+		expansions := expand(`$for(m in modules)$$m$-$for(e in envs)$$e$$endfor$$endfor$`)
+		So(len(expansions), ShouldEqual, 4)
+		So(string(expansions[1].Text), ShouldEqual, "api-prod")
+		So(expansions[1].Bindings, ShouldResemble, map[string]string{"m": "api", "e": "prod"})
+		So(string(expansions[2].Text), ShouldEqual, "worker-dev")
+	})
+
+	Convey("A template without loops expands to its output", t, func() {
+		So(expand(`$modules$`), ShouldResemble, []Expansion{
+			{Text: []byte("api,worker"), Bindings: map[string]string{}},
+		})
+	})
+}
+
+func TestInclude(t *testing.T) {
+	partials := map[string]string{
+		"partials/header.txt": "// $name$ (c) $year$\n",
+		"partials/item.txt":   "- $m;format=\"upper\"$\n",
+		"partials/typo.txt":   "$nmae$",
+		"partials/a.txt":      `$include("partials/b.txt")$`,
+		"partials/b.txt":      `$include("partials/a.txt")$`,
+	}
+	load := func(name string) ([]byte, error) {
+		text, ok := partials[name]
+		if !ok {
+			return nil, fmt.Errorf("open %s: no such file", name)
+		}
+		return []byte(text), nil
+	}
+	fields := map[string]string{"name": "shop", "year": "2015", "modules": "api,cli"}
+
+	execute := func(text string, opt string) (string, error) {
+		template, err := New("main.go").Option(opt).Includes(load).Parse([]byte(text))
+		So(err, ShouldBeNil)
+		return template.render(fields)
+	}
+
+	Convey("A partial is rendered in place with the current answers", t, func() {
+		value, err := execute("$include(\"partials/header.txt\")$\npackage main\n", "missingkey=default")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "// shop (c) 2015\npackage main\n")
+	})
+
+	Convey("Loop variables are visible to partials", t, func() {
+		value, err := execute("$for(m in modules)$\n$include(\"partials/item.txt\")$\n$endfor$\n", "missingkey=default")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, "- API\n- CLI\n")
+	})
+
+	Convey("Include cycles are errors", t, func() {
+		_, err := execute(`$include("partials/a.txt")$`, "missingkey=default")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `template: partials/b.txt:1:1: include cycle: partials/a.txt -> partials/b.txt -> partials/a.txt in $include("partials/a.txt")$`)
+	})
+
+	Convey("Missing partials are errors", t, func() {
+		_, err := execute(`x $include("partials/nope.txt")$`, "missingkey=default")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `template: main.go:1:3: open partials/nope.txt: no such file in $include("partials/nope.txt")$`)
+	})
+
+	Convey("Undefined fields are reported against the partial holding them", t, func() {
+		_, err := execute(`$include("partials/typo.txt")$$include("partials/typo.txt")$`, "missingkey=error")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `template: partials/typo.txt:1:1: undefined field "nmae" in $nmae$; did you mean "name"?`)
+	})
+
+	Convey("Templates without a loader cannot include partials", t, func() {
+		_, err := Render([]byte(`$include("partials/header.txt")$`), fields)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "includes are not supported")
+	})
+	Convey("Partials are read and parsed once however often they're included", t, func() {
+		loads := 0
+		partials := NewPartials(func(name string) ([]byte, error) {
+			loads++
+			return []byte("[$m;format=\"upper\"$]"), nil
+		})
+
+		for _, name := range []string{"a.txt", "b.txt"} {
+			tmpl, err := New(name).IncludesFrom(partials).Parse([]byte(`$for(m in modules)$$include("item.txt")$$endfor$`))
+			So(err, ShouldBeNil)
+
+			buffer := &bytes.Buffer{}
+			So(tmpl.Execute(buffer, map[string]string{"modules": "api,cli"}), ShouldBeNil)
+			So(buffer.String(), ShouldEqual, "[API][CLI]")
+		}
+		So(loads, ShouldEqual, 1)
+	})
+}
+
+func TestDirLoader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "partials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "header.txt"), []byte("hello"), 0644)
+
+	load := DirLoader(dir)
+
+	Convey("Partials are read relative to the root", t, func() {
+		text, err := load("header.txt")
+		So(err, ShouldBeNil)
+		So(string(text), ShouldEqual, "hello")
+	})
+
+	Convey("Partials outside of the root are rejected", t, func() {
+		for _, name := range []string{"../header.txt", "/etc/passwd", "a/../../header.txt"} {
+			_, err := load(name)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "outside of the template")
+		}
+	})
+}
+
+// benchmarkText is a template of a few thousand lines with an expression on
+// every line
+var benchmarkText = bytes.Repeat([]byte("val $name;format=\"snake\"$ = \"$package$\" // \\$1 $if(docker.truthy)$docker$endif$\n"), 2000)
+
+var benchmarkFields = map[string]string{"name": "Order Service", "package": "com.acme", "docker": "yes"}
+
+func BenchmarkRender(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := Render(benchmarkText, benchmarkFields); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExecute(b *testing.B) {
+	template, err := Parse(benchmarkText)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := template.Execute(ioutil.Discard, benchmarkFields); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExecuteUndefined(b *testing.B) {
+	template, err := New("test").Option("missingkey=error").Parse(benchmarkText)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := template.Execute(ioutil.Discard, map[string]string{}); err == nil {
+			b.Fatal("expected undefined fields")
+		}
+	}
+}
+
+func BenchmarkExecuteInclude(b *testing.B) {
+	partial := bytes.Repeat([]byte("// $name;format=\"upper\"$ $m$ (c) $year;format=\"default(\"2015\")\"$\n"), 50)
+	load := func(name string) ([]byte, error) {
+		return partial, nil
+	}
+	fields := map[string]string{"name": "Order Service", "modules": strings.Repeat("api,", 49) + "api"}
+
+	template, err := New("test").Includes(load).Parse([]byte(`$for(m in modules)$$include("header.txt")$$endfor$`))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := template.Execute(ioutil.Discard, fields); err != nil {
+			b.Fatal(err)
+		}
+	}
 }
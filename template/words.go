@@ -0,0 +1,167 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SplitWords splits value into words, understanding camelCase, PascalCase,
+// snake_case, kebab-case, dot.case and space separated input, e.g.
+// "myHTTPServer_v2-api" -> [my HTTP Server v2 api].  Any character that is not
+// a letter, digit or combining mark separates words.  Within a run of letters
+// a word starts at a lower to upper case transition or, for a run of capitals,
+// at the last capital before a lower case letter.  Digits belong to the word
+// they follow.
+func SplitWords(value string) []string {
+	words := []string{}
+	runes := []rune(value)
+
+	start := -1 // start of the current word, or -1 when between words
+	for i, r := range runes {
+		if !isWordRune(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+
+		if start >= 0 && i > start && boundary(runes, i) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// boundary reports whether a new word starts at runes[i], given runes[i-1] is
+// part of the current word
+func boundary(runes []rune, i int) bool {
+	if !unicode.IsUpper(runes[i]) {
+		return false
+	}
+
+	// skip back over combining marks to find the previous base character
+	j := i - 1
+	for j > 0 && unicode.IsMark(runes[j]) {
+		j--
+	}
+	previous := runes[j]
+
+	switch {
+	case unicode.IsLower(previous) || unicode.IsDigit(previous):
+		return true // myCool, v2Api
+	case unicode.IsUpper(previous):
+		// HTTPServer splits before the S, the last capital before a lower case letter
+		return i+1 < len(runes) && unicode.IsLower(runes[i+1])
+	default:
+		return false
+	}
+}
+
+// mapWords splits value into words, applies mapping to each and joins the
+// results with sep
+func mapWords(value string, sep string, mapping func(string) string) string {
+	words := SplitWords(value)
+	for index, word := range words {
+		words[index] = mapping(word)
+	}
+	return strings.Join(words, sep)
+}
+
+// Kebab converts value to kebab-case e.g. myCoolService -> my-cool-service
+func Kebab(value string) string {
+	return mapWords(value, "-", strings.ToLower)
+}
+
+// Constant converts value to CONSTANT_CASE e.g. myCoolService -> MY_COOL_SERVICE
+func Constant(value string) string {
+	return mapWords(value, "_", strings.ToUpper)
+}
+
+// DotCase converts value to dot.case e.g. myCoolService -> my.cool.service
+func DotCase(value string) string {
+	return mapWords(value, ".", strings.ToLower)
+}
+
+// PathCase converts value to path/case e.g. myCoolService -> my/cool/service
+func PathCase(value string) string {
+	return mapWords(value, "/", strings.ToLower)
+}
+
+// Title converts value to Title Case e.g. my_cool-service -> My Cool Service
+func Title(value string) string {
+	return mapWords(value, " ", func(word string) string {
+		return Capitalize(strings.ToLower(word))
+	})
+}
+
+// initialisms that Go style keeps in a single case within identifiers
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "LHS": true, "QPS": true, "RAM": true, "RHS": true,
+	"RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true,
+	"URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true, "XMPP": true,
+	"XSRF": true, "XSS": true,
+}
+
+// GoExported converts value to an exported Go identifier, keeping common
+// initialisms in upper case e.g. my_http_server_id -> MyHTTPServerID.  Only
+// letters, digits and underscores are kept, since combining marks aren't
+// allowed in identifiers, and an X is prepended if the result wouldn't
+// otherwise start with an upper case letter e.g. 名前 -> X名前.  Values with
+// nothing left to keep, such as "" or "--", become X.
+func GoExported(value string) string {
+	identifier := mapWords(value, "", func(word string) string {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			return upper
+		}
+		return Capitalize(strings.ToLower(word))
+	})
+	identifier = strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, identifier)
+
+	if r := []rune(identifier); len(r) == 0 || !unicode.IsUpper(r[0]) {
+		identifier = "X" + identifier
+	}
+	return identifier
+}
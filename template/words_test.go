@@ -0,0 +1,100 @@
+// The MIT License (MIT)
+//
+// Copyright (c) 2014 Matt Ho
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package template
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+// wordConformance covers the formatters built on SplitWords
+var wordConformance = []formatterCase{
+	{[]string{"kebab", "kebab-case"}, "myCoolService", "my-cool-service"},
+	{[]string{"constant", "constant-case"}, "my-cool.service", "MY_COOL_SERVICE"},
+	{[]string{"dot", "dot-case"}, "MyCool_service", "my.cool.service"},
+	{[]string{"path", "path-case"}, "my cool-service", "my/cool/service"},
+	{[]string{"title", "title-case"}, "my_cool-SERVICE", "My Cool Service"},
+	{[]string{"exported", "go-exported"}, "my_http_server_id", "MyHTTPServerID"},
+}
+
+func TestSplitWords(t *testing.T) {
+	Convey("#SplitWords understands the common casing conventions", t, func() {
+		for input, expected := range map[string][]string{
+			"myCoolService":         {"my", "Cool", "Service"},
+			"MyCoolService":         {"My", "Cool", "Service"},
+			"my_cool_service":       {"my", "cool", "service"},
+			"my-cool-service":       {"my", "cool", "service"},
+			"my.cool.service":       {"my", "cool", "service"},
+			"my cool  service":      {"my", "cool", "service"},
+			"MY_COOL_SERVICE":       {"MY", "COOL", "SERVICE"},
+			"HTTPServer":            {"HTTP", "Server"},
+			"parseHTTPRequest":      {"parse", "HTTP", "Request"},
+			"myHTTPServer_v2-api":   {"my", "HTTP", "Server", "v2", "api"},
+			"v2Api":                 {"v2", "Api"},
+			"utf8":                  {"utf8"},
+			"__leading--trailing__": {"leading", "trailing"},
+			"émileService":          {"émile", "Service"},
+			"émileÉcole":          {"émile", "École"},
+			"订单服务-api":              {"订单服务", "api"},
+			"":                      {},
+			"-_.":                   {},
+		} {
+			So(SplitWords(input), ShouldResemble, expected)
+		}
+	})
+}
+
+func TestWordCases(t *testing.T) {
+	Convey("Each conversion works from any input casing", t, func() {
+		for _, input := range []string{"myCoolService", "MyCoolService", "my_cool_service", "my-cool-service", "my.cool.service", "My Cool Service"} {
+			So(Kebab(input), ShouldEqual, "my-cool-service")
+			So(Constant(input), ShouldEqual, "MY_COOL_SERVICE")
+			So(DotCase(input), ShouldEqual, "my.cool.service")
+			So(PathCase(input), ShouldEqual, "my/cool/service")
+			So(Title(input), ShouldEqual, "My Cool Service")
+			So(GoExported(input), ShouldEqual, "MyCoolService")
+		}
+	})
+
+	Convey("#GoExported keeps initialisms and always produces an identifier", t, func() {
+		So(GoExported("user id"), ShouldEqual, "UserID")
+		So(GoExported("json-api-url"), ShouldEqual, "JSONAPIURL")
+		So(GoExported("HttpsProxy"), ShouldEqual, "HTTPSProxy")
+		So(GoExported("2fa-service"), ShouldEqual, "X2faService")
+		So(GoExported("émile"), ShouldEqual, "Émile")
+		So(GoExported(""), ShouldEqual, "X")
+		So(GoExported("--!"), ShouldEqual, "X")
+	})
+
+	Convey("#GoExported drops combining marks and exports caseless names", t, func() {
+		for input, expected := range map[string]string{
+			"e\u0301mile":      "Emile",
+			"cafe\u0301 order": "CafeOrder",
+			"नमस्ते":           "Xनमसत",
+			"名前":               "X名前",
+			"名前 service":       "X名前Service",
+		} {
+			So(GoExported(input), ShouldEqual, expected)
+		}
+	})
+}